@@ -0,0 +1,718 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and deploy a VM from one of three sources
+//
+//			  -template /DC0/vm/ubuntu-tmpl      clone a VM or template
+//			  -library lib/item                  deploy a content library OVF or VM template item
+//			  -ovf ./appliance.ova               import a local OVF (with its disks alongside) or OVA
+//
+//			The VM lands in -cluster/-pool/-host, -datastore, -folder and -network. When a cluster is
+//			given without a host or datastore, the cluster is asked for a placement recommendation
+//			(-place only prints it). Guest customization comes from a saved spec (-spec) or from
+//			-hostname/-domain/-ip/-gateway/-dns for a Linux guest.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/ovf"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vapi/library"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/vcenter"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//
+// Target is where the new VM goes. Pool and Folder are always set once resolve has run, the others only
+// when they were asked for or recommended by the cluster.
+//
+
+type Target struct {
+	Cluster   *object.ClusterComputeResource
+	Host      *object.HostSystem
+	Pool      *object.ResourcePool
+	Datastore *object.Datastore
+	Folder    *object.Folder
+	Network   object.NetworkReference
+}
+
+func resolve(ctx context.Context, finder *find.Finder, dc *object.Datacenter, cluster, host, pool, datastore, folder, network string) (*Target, error) {
+	t := new(Target)
+	var err error
+
+	if cluster != "" {
+		if t.Cluster, err = finder.ClusterComputeResource(ctx, cluster); err != nil {
+			return nil, err
+		}
+	}
+	if host != "" {
+		if t.Host, err = finder.HostSystem(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+	if datastore != "" {
+		if t.Datastore, err = finder.Datastore(ctx, datastore); err != nil {
+			return nil, err
+		}
+	}
+	if network != "" {
+		if t.Network, err = finder.Network(ctx, network); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case pool != "":
+		t.Pool, err = finder.ResourcePool(ctx, pool)
+	case t.Cluster != nil:
+		t.Pool, err = t.Cluster.ResourcePool(ctx)
+	case t.Host != nil:
+		t.Pool, err = t.Host.ResourcePool(ctx)
+	default:
+		t.Pool, err = finder.DefaultResourcePool(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if folder != "" {
+		t.Folder, err = finder.Folder(ctx, folder)
+	} else {
+		var folders *object.DatacenterFolders
+		if folders, err = dc.Folders(ctx); err == nil {
+			t.Folder = folders.VmFolder
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+//
+// place asks the cluster (DRS) where a new VM should go and fills in the host and datastore that were not given.
+// For a clone the source VM is passed so that its size is taken into account.
+//
+
+func place(ctx context.Context, c *vim25.Client, t *Target, name string, source *object.VirtualMachine) (string, error) {
+	spec := types.PlacementSpec{
+		PlacementType: string(types.PlacementSpecPlacementTypeCreate),
+		ConfigSpec:    &types.VirtualMachineConfigSpec{Name: name},
+	}
+	if source != nil {
+		ref := source.Reference()
+		spec.PlacementType = string(types.PlacementSpecPlacementTypeClone)
+		spec.Vm = &ref
+		spec.CloneName = name
+		spec.CloneSpec = &types.VirtualMachineCloneSpec{}
+		spec.ConfigSpec = nil
+	}
+
+	res, err := t.Cluster.PlaceVm(ctx, spec)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rec := range res.Recommendations {
+		for _, a := range rec.Action {
+			action, ok := a.(*types.PlacementAction)
+			if !ok || action.RelocateSpec == nil {
+				continue
+			}
+
+			if t.Host == nil && action.TargetHost != nil {
+				t.Host = object.NewHostSystem(c, *action.TargetHost)
+			}
+			if t.Datastore == nil && action.RelocateSpec.Datastore != nil {
+				t.Datastore = object.NewDatastore(c, *action.RelocateSpec.Datastore)
+			}
+			return rec.ReasonText, nil
+		}
+	}
+
+	return "", fmt.Errorf("cluster %s made no placement recommendation", t.Cluster.Name())
+}
+
+// objectName looks up the name of a managed object, for printing
+func objectName(ctx context.Context, c *vim25.Client, obj mo.Reference) string {
+	var e mo.ManagedEntity
+	if err := property.DefaultCollector(c).RetrieveOne(ctx, obj.Reference(), []string{"name"}, &e); err != nil {
+		return obj.Reference().Value
+	}
+	return e.Name
+}
+
+//
+// customization builds a guest customization spec, either a saved one (-spec) or a Linux one from the flags.
+// -ip takes an address with its prefix length (10.0.0.5/24) or "dhcp".
+//
+
+func customization(ctx context.Context, c *vim25.Client, specName, hostname, domain, ip, gateway string, dns []string) (*types.CustomizationSpec, error) {
+	if specName != "" {
+		item, err := object.NewCustomizationSpecManager(c).GetCustomizationSpec(ctx, specName)
+		if err != nil {
+			return nil, err
+		}
+		return &item.Spec, nil
+	}
+
+	if hostname == "" && ip == "" {
+		return nil, nil
+	}
+	if hostname == "" {
+		return nil, fmt.Errorf("-ip also needs -hostname")
+	}
+
+	adapter := types.CustomizationIPSettings{Ip: &types.CustomizationDhcpIpGenerator{}}
+
+	if ip != "" && ip != "dhcp" {
+		addr, network, err := net.ParseCIDR(ip)
+		if err != nil {
+			return nil, fmt.Errorf("-ip wants address/prefix or dhcp, got %q", ip)
+		}
+		adapter.Ip = &types.CustomizationFixedIp{IpAddress: addr.String()}
+		adapter.SubnetMask = net.IP(network.Mask).String()
+		if gateway != "" {
+			adapter.Gateway = []string{gateway}
+		}
+	}
+	adapter.DnsServerList = dns
+
+	return &types.CustomizationSpec{
+		Identity: &types.CustomizationLinuxPrep{
+			HostName: &types.CustomizationFixedName{Name: hostname},
+			Domain:   domain,
+		},
+		GlobalIPSettings: types.CustomizationGlobalIPSettings{DnsServerList: dns},
+		NicSettingMap:    []types.CustomizationAdapterMapping{{Adapter: adapter}},
+	}, nil
+}
+
+//
+// cloneVM clones a VM or template. The first network adapter is moved to -network, if one was given.
+//
+
+func cloneVM(ctx context.Context, c *vim25.Client, source *object.VirtualMachine, name string, t *Target, custom *types.CustomizationSpec) (*object.VirtualMachine, error) {
+	relocate := types.VirtualMachineRelocateSpec{}
+
+	pool := t.Pool.Reference()
+	relocate.Pool = &pool
+	if t.Host != nil {
+		host := t.Host.Reference()
+		relocate.Host = &host
+	}
+	if t.Datastore != nil {
+		ds := t.Datastore.Reference()
+		relocate.Datastore = &ds
+	}
+
+	if t.Network != nil {
+		devices, err := source.Device(ctx)
+		if err != nil {
+			return nil, err
+		}
+		nics := devices.SelectByType((*types.VirtualEthernetCard)(nil))
+		if len(nics) > 0 {
+			backing, err := t.Network.EthernetCardBackingInfo(ctx)
+			if err != nil {
+				return nil, err
+			}
+			nic := nics[0].(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+			nic.Backing = backing
+			relocate.DeviceChange = append(relocate.DeviceChange, &types.VirtualDeviceConfigSpec{
+				Operation: types.VirtualDeviceConfigSpecOperationEdit,
+				Device:    nics[0],
+			})
+		}
+	}
+
+	task, err := source.Clone(ctx, t.Folder, name, types.VirtualMachineCloneSpec{
+		Location:      relocate,
+		Customization: custom,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := task.WaitForResult(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return object.NewVirtualMachine(c, info.Result.(types.ManagedObjectReference)), nil
+}
+
+//
+// deployLibraryItem deploys a content library item given as library/item. OVF items go through the OVF
+// deployment API with every OVF network mapped to -network, VM template items through the VM template API.
+//
+
+func deployLibraryItem(ctx context.Context, c *vim25.Client, rc *rest.Client, path, name string, t *Target) (*object.VirtualMachine, error) {
+	i := strings.Index(path, "/")
+	if i <= 0 {
+		return nil, fmt.Errorf("-library wants library/item, got %q", path)
+	}
+
+	m := library.NewManager(rc)
+
+	lib, err := m.GetLibraryByName(ctx, path[:i])
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := m.FindLibraryItems(ctx, library.FindItem{LibraryID: lib.ID, Name: path[i+1:]})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != 1 {
+		return nil, fmt.Errorf("found %d items called %s in library %s", len(ids), path[i+1:], lib.Name)
+	}
+
+	item, err := m.GetLibraryItem(ctx, ids[0])
+	if err != nil {
+		return nil, err
+	}
+
+	vc := vcenter.NewManager(rc)
+
+	var ref *types.ManagedObjectReference
+
+	switch item.Type {
+	case library.ItemTypeOVF:
+		target := vcenter.Target{ResourcePoolID: t.Pool.Reference().Value, FolderID: t.Folder.Reference().Value}
+		if t.Host != nil {
+			target.HostID = t.Host.Reference().Value
+		}
+
+		spec := vcenter.DeploymentSpec{Name: name, AcceptAllEULA: true}
+		if t.Datastore != nil {
+			spec.DefaultDatastoreID = t.Datastore.Reference().Value
+		}
+
+		if t.Network != nil {
+			filter, err := vc.FilterLibraryItem(ctx, item.ID, vcenter.FilterRequest{Target: target})
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range filter.Networks {
+				spec.NetworkMappings = append(spec.NetworkMappings, vcenter.NetworkMapping{Key: n, Value: t.Network.Reference().Value})
+			}
+		}
+
+		ref, err = vc.DeployLibraryItem(ctx, item.ID, vcenter.Deploy{DeploymentSpec: spec, Target: target})
+
+	case library.ItemTypeVMTX:
+		deploy := vcenter.DeployTemplate{
+			Name: name,
+			Placement: &vcenter.Placement{
+				ResourcePool: t.Pool.Reference().Value,
+				Folder:       t.Folder.Reference().Value,
+			},
+		}
+		if t.Host != nil {
+			deploy.Placement.Host = t.Host.Reference().Value
+		}
+		if t.Datastore != nil {
+			deploy.DiskStorage = &vcenter.DiskStorage{Datastore: t.Datastore.Reference().Value}
+			deploy.VMHomeStorage = deploy.DiskStorage
+		}
+
+		ref, err = vc.DeployTemplateLibraryItem(ctx, item.ID, deploy)
+
+	default:
+		return nil, fmt.Errorf("library item %s is of type %s, which cannot be deployed", path, item.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return object.NewVirtualMachine(c, *ref), nil
+}
+
+//
+// archive gives access to the files of an OVF - either the directory the .ovf file is in, or the tar file of
+// an OVA. An OVA is read again from the start for each file, which keeps memory use flat.
+//
+
+type archive struct {
+	path string
+	ova  bool
+}
+
+func (a archive) open(name string) (io.ReadCloser, int64, error) {
+	if !a.ova {
+		f, err := os.Open(filepath.Join(filepath.Dir(a.path), name))
+		if err != nil {
+			return nil, 0, err
+		}
+		s, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, s.Size(), nil
+	}
+
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r := tar.NewReader(f)
+	for {
+		h, err := r.Next()
+		if err != nil {
+			f.Close()
+			if err == io.EOF {
+				err = fmt.Errorf("%s not found in %s", name, a.path)
+			}
+			return nil, 0, err
+		}
+
+		if filepath.Base(h.Name) == name || (name == "*.ovf" && filepath.Ext(h.Name) == ".ovf") {
+			return struct {
+				io.Reader
+				io.Closer
+			}{r, f}, h.Size, nil
+		}
+	}
+}
+
+//
+// importOVF imports a local OVF/OVA - the descriptor is turned into an import spec, then the disks are uploaded
+// through the NFC lease that ImportVApp hands back
+//
+
+func importOVF(ctx context.Context, c *vim25.Client, file, name string, t *Target) (*object.VirtualMachine, error) {
+	a := archive{path: file, ova: strings.EqualFold(filepath.Ext(file), ".ova")}
+
+	descriptorName := filepath.Base(file)
+	if a.ova {
+		descriptorName = "*.ovf"
+	}
+
+	r, _, err := a.open(descriptorName)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := ovf.Unmarshal(strings.NewReader(string(descriptor)))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse OVF descriptor: %s", err)
+	}
+
+	cisp := types.OvfCreateImportSpecParams{EntityName: name}
+	if t.Host != nil {
+		host := t.Host.Reference()
+		cisp.HostSystem = &host
+	}
+	if t.Network != nil && envelope.Network != nil {
+		for _, n := range envelope.Network.Networks {
+			cisp.NetworkMapping = append(cisp.NetworkMapping, types.OvfNetworkMapping{Name: n.Name, Network: t.Network.Reference()})
+		}
+	}
+
+	spec, err := ovf.NewManager(c).CreateImportSpec(ctx, string(descriptor), t.Pool, t.Datastore, cisp)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Error != nil {
+		return nil, fmt.Errorf("%s", spec.Error[0].LocalizedMessage)
+	}
+	for _, w := range spec.Warning {
+		fmt.Printf("! %s\n", w.LocalizedMessage)
+	}
+
+	lease, err := t.Pool.ImportVApp(ctx, spec.ImportSpec, t.Folder, t.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := lease.Wait(ctx, spec.FileItem)
+	if err != nil {
+		return nil, err
+	}
+
+	updater := lease.StartUpdater(ctx, info)
+	defer updater.Done()
+
+	for _, item := range info.Items {
+		f, size, err := a.open(item.Path)
+		if err != nil {
+			_ = lease.Abort(ctx, nil)
+			return nil, err
+		}
+
+		fmt.Printf("  uploading %s\n", item.Path)
+		err = lease.Upload(ctx, item, f, soap.Upload{ContentLength: size})
+		f.Close()
+		if err != nil {
+			_ = lease.Abort(ctx, nil)
+			return nil, err
+		}
+	}
+
+	if err = lease.Complete(ctx); err != nil {
+		return nil, err
+	}
+
+	return object.NewVirtualMachine(c, info.Entity), nil
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var name, template, item, ovfFile string
+	var dcName, cluster, host, pool, datastore, folder, network string
+	var specName, hostname, domain, ip, gateway string
+	var dns stringList
+	var placeOnly, powerOn bool
+
+	flag.StringVar(&name, "name", "", "name of the new VM")
+	flag.StringVar(&template, "template", "", "clone this VM or template (name or inventory path)")
+	flag.StringVar(&item, "library", "", "deploy this content library item, as library/item")
+	flag.StringVar(&ovfFile, "ovf", "", "import this local .ovf or .ova file")
+	flag.StringVar(&dcName, "datacenter", "", "datacenter (needed when there is more than one)")
+	flag.StringVar(&cluster, "cluster", "", "target cluster")
+	flag.StringVar(&host, "host", "", "target host")
+	flag.StringVar(&pool, "pool", "", "target resource pool (default: the cluster's or host's root pool)")
+	flag.StringVar(&datastore, "datastore", "", "target datastore")
+	flag.StringVar(&folder, "folder", "", "target VM folder (default: the datacenter's VM folder)")
+	flag.StringVar(&network, "network", "", "network for the VM's (first) network adapter")
+	flag.StringVar(&specName, "spec", "", "apply this saved customization spec")
+	flag.StringVar(&hostname, "hostname", "", "guest host name (Linux customization)")
+	flag.StringVar(&domain, "domain", "", "guest domain (Linux customization)")
+	flag.StringVar(&ip, "ip", "", "guest address as 10.0.0.5/24, or dhcp (Linux customization)")
+	flag.StringVar(&gateway, "gateway", "", "guest default gateway (Linux customization)")
+	flag.Var(&dns, "dns", "guest DNS server (repeatable)")
+	flag.BoolVar(&placeOnly, "place", false, "only print the placement recommendation")
+	flag.BoolVar(&powerOn, "power-on", false, "power the VM on once it is deployed")
+	flag.Parse()
+
+	sources := 0
+	for _, s := range []string{template, item, ovfFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if name == "" || sources != 1 {
+		fmt.Printf("usage: deploy-vm -name vm (-template vm | -library lib/item | -ovf file) [-cluster c] [-host h] [-pool p] [-datastore ds] [-folder f] [-network n]\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	finder := find.NewFinder(c)
+
+	dc, err := finder.DatacenterOrDefault(ctx, dcName)
+	if err != nil {
+		fmt.Printf("Could not find datacenter, error %v\n", err)
+		return
+	}
+	finder.SetDatacenter(dc)
+
+	t, err := resolve(ctx, finder, dc, cluster, host, pool, datastore, folder, network)
+	if err != nil {
+		fmt.Printf("Could not find deployment target, error %v\n", err)
+		return
+	}
+
+	var source *object.VirtualMachine
+	if template != "" {
+		if source, err = finder.VirtualMachine(ctx, template); err != nil {
+			fmt.Printf("Could not find template %s, error %v\n", template, err)
+			return
+		}
+	}
+
+	custom, err := customization(ctx, c, specName, hostname, domain, ip, gateway, dns)
+	if err != nil {
+		fmt.Printf("Could not build the customization spec, error %v\n", err)
+		return
+	}
+
+	//
+	// Let the cluster recommend a host and datastore for anything that was not given. A local OVF import needs a
+	// datastore, so a standalone host falls back to the default datastore.
+	//
+
+	if t.Cluster != nil && (t.Host == nil || t.Datastore == nil) {
+		reason, err := place(ctx, c, t, name, source)
+		if err != nil {
+			fmt.Printf("Could not get a placement recommendation, error %v\n", err)
+			return
+		}
+		fmt.Printf("\nPlacement recommendation from %s (%s):\n", t.Cluster.Name(), reason)
+		if t.Host != nil {
+			fmt.Printf("  Host:      %s\n", objectName(ctx, c, t.Host))
+		}
+		if t.Datastore != nil {
+			fmt.Printf("  Datastore: %s\n", objectName(ctx, c, t.Datastore))
+		}
+		fmt.Println()
+	} else if placeOnly {
+		fmt.Printf("-place needs a -cluster to ask for a recommendation\n")
+		return
+	}
+
+	if placeOnly {
+		return
+	}
+
+	if t.Datastore == nil && ovfFile != "" {
+		if t.Datastore, err = finder.DefaultDatastore(ctx); err != nil {
+			fmt.Printf("Could not find a datastore (use -datastore), error %v\n", err)
+			return
+		}
+	}
+
+	var vm *object.VirtualMachine
+
+	switch {
+	case source != nil:
+		fmt.Printf("Cloning %s to %s\n", template, name)
+		vm, err = cloneVM(ctx, c, source, name, t, custom)
+		custom = nil
+
+	case item != "":
+		fmt.Printf("Deploying library item %s to %s\n", item, name)
+		rc := rest.NewClient(c)
+		if err = rc.Login(ctx, url.UserPassword(user, pwd)); err != nil {
+			fmt.Printf("Could not log in to the content library service, error %v\n", err)
+			return
+		}
+		defer rc.Logout(ctx)
+		vm, err = deployLibraryItem(ctx, c, rc, item, name, t)
+
+	default:
+		fmt.Printf("Importing %s to %s\n", ovfFile, name)
+		vm, err = importOVF(ctx, c, ovfFile, name, t)
+	}
+
+	if err != nil {
+		fmt.Printf("Deployment failed, error %v\n", err)
+		return
+	}
+
+	// A clone is customized as part of the clone, the other sources once they exist
+
+	if custom != nil {
+		fmt.Printf("Customizing %s\n", name)
+		task, err := vm.Customize(ctx, *custom)
+		if err == nil {
+			err = task.Wait(ctx)
+		}
+		if err != nil {
+			fmt.Printf("Customization failed, error %v\n", err)
+			return
+		}
+	}
+
+	if powerOn {
+		task, err := vm.PowerOn(ctx)
+		if err == nil {
+			err = task.Wait(ctx)
+		}
+		if err != nil {
+			fmt.Printf("Could not power on %s, error %v\n", name, err)
+			return
+		}
+	}
+
+	path, err := find.InventoryPath(ctx, c, vm.Reference())
+	if err != nil {
+		path = name
+	}
+	fmt.Printf("Deployed %s (%s)\n", path, vm.Reference().Value)
+}
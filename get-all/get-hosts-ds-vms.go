@@ -13,19 +13,150 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/session/cache"
 	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
+//
+// tagFilter holds the -tag category:name values. With -tag-match all (the default) an object must carry every
+// tag, with -tag-match any it needs at least one of them.
+//
+
+type tagFilter struct {
+	tags    []string
+	match   string
+	objects map[types.ManagedObjectReference]bool
+}
+
+func (f *tagFilter) String() string { return strings.Join(f.tags, ",") }
+
+func (f *tagFilter) Set(value string) error {
+	if i := strings.Index(value, ":"); i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("want category:name, got %q", value)
+	}
+	f.tags = append(f.tags, value)
+	return nil
+}
+
+//
+// resolve finds the objects that pass the filter. Tags live behind the vAPI (REST) endpoint, which needs its own
+// login, and GetAttachedObjectsOnTags returns the objects for all of the tags in one call.
+//
+
+func (f *tagFilter) resolve(ctx context.Context, c *vim25.Client, user *url.Userinfo) error {
+	if len(f.tags) == 0 {
+		return nil
+	}
+	if f.match != "all" && f.match != "any" {
+		return fmt.Errorf("-tag-match must be \"all\" or \"any\", not %q", f.match)
+	}
+
+	rc := rest.NewClient(c)
+	if err := rc.Login(ctx, user); err != nil {
+		return fmt.Errorf("could not log in to the tagging service: %s", err)
+	}
+	defer rc.Logout(ctx)
+
+	m := tags.NewManager(rc)
+
+	var ids []string
+	for _, name := range f.tags {
+		i := strings.Index(name, ":")
+		tag, err := m.GetTagForCategory(ctx, name[i+1:], name[:i])
+		if err != nil {
+			return fmt.Errorf("could not find tag %s: %s", name, err)
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	attached, err := m.GetAttachedObjectsOnTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("could not get the objects tagged %s: %s", f, err)
+	}
+
+	count := make(map[types.ManagedObjectReference]int)
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			count[obj.Reference()]++
+		}
+	}
+
+	f.objects = make(map[types.ManagedObjectReference]bool)
+	for ref, n := range count {
+		if f.match == "any" || n == len(ids) {
+			f.objects[ref] = true
+		}
+	}
+
+	return nil
+}
+
+// keep reports whether an object passes the filter - everything passes when no -tag was given
+func (f *tagFilter) keep(ref types.ManagedObjectReference) bool {
+	return len(f.tags) == 0 || f.objects[ref]
+}
+
+//
+// customFields returns the custom attribute definitions that apply to a managed object type - the ones defined
+// for that type plus the global ones - sorted by name. Only vCenter has a custom fields manager.
+//
+
+func customFields(ctx context.Context, c *vim25.Client, moType string) ([]types.CustomFieldDef, error) {
+	m, err := object.GetCustomFieldsManager(c)
+	if err == object.ErrNotSupported {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := m.Field(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []types.CustomFieldDef
+	for _, def := range all {
+		if def.ManagedObjectType == "" || def.ManagedObjectType == moType {
+			fields = append(fields, def)
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	return fields, nil
+}
+
+// customValue returns the value an object holds for a custom attribute, or "-" when it is not set
+func customValue(values []types.BaseCustomFieldValue, key int32) string {
+	value := ""
+	for _, v := range values {
+		if sv, ok := v.(*types.CustomFieldStringValue); ok && sv.Key == key {
+			value = sv.Value
+		}
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 func main() {
 
 	// We need to get 3 environment variables in order to connect to the vSphere infra
@@ -34,6 +165,13 @@ func main() {
 	// GOVMOMI_USERNAME
 	// GOVMOMI_PASSWORD
 	//
+	// -tag category:name (repeatable) scopes the report to tagged hosts, datastores and VMs
+	//
+
+	var filter tagFilter
+	flag.Var(&filter, "tag", "only report objects with this category:name tag (repeat for more than one tag)")
+	flag.StringVar(&filter.match, "tag-match", "all", "with more than one -tag, objects must have \"all\" of the tags or \"any\" of them")
+	flag.Parse()
 
 	vc := os.Getenv("GOVMOMI_URL")
 	user := os.Getenv("GOVMOMI_USERNAME")
@@ -93,6 +231,12 @@ func main() {
 	} else {
 		fmt.Println("Log in successful")
 
+		err = filter.resolve(ctx, c, u.User)
+		if err != nil {
+			fmt.Printf("Unable to apply tag filter: error %s\n", err)
+			return
+		}
+
 		//
 		// Create a view manager - a mechanism that supports selection of objects on the server and subsequently, access to those objects.
 		//
@@ -123,13 +267,23 @@ func main() {
 
 		var hss []mo.HostSystem
 
-		err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"summary"}, &hss)
+		err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"summary", "customValue"}, &hss)
 
 		if err != nil {
 			fmt.Printf("Unable to retrieve Host information: error %s", err)
 			return
 		}
 
+		//
+		// Custom attributes (custom fields) defined for hosts become extra columns
+		//
+
+		hostFields, err := customFields(ctx, c, "HostSystem")
+		if err != nil {
+			fmt.Printf("Unable to retrieve custom attribute definitions: error %s", err)
+			return
+		}
+
 		//
 		// Print summary per host (see also: govc/host/info.go)
 		//
@@ -137,9 +291,16 @@ func main() {
 		tw := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
 		fmt.Printf("\n*** Host Information ***\n")
 		fmt.Printf("------------------------\n\n")
-		fmt.Fprintf(tw, "Name:\tUsed CPU:\tTotal CPU:\tFree CPU:\tUsed Memory:\tTotal Memory:\tFree Memory:\t\n")
+		fmt.Fprintf(tw, "Name:\tUsed CPU:\tTotal CPU:\tFree CPU:\tUsed Memory:\tTotal Memory:\tFree Memory:\t")
+		for _, field := range hostFields {
+			fmt.Fprintf(tw, "%s:\t", field.Name)
+		}
+		fmt.Fprintf(tw, "\n")
 
 		for _, hs := range hss {
+			if !filter.keep(hs.Reference()) {
+				continue
+			}
 			totalCPU := int64(hs.Summary.Hardware.CpuMhz) * int64(hs.Summary.Hardware.NumCpuCores)
 			freeCPU := int64(totalCPU) - int64(hs.Summary.QuickStats.OverallCpuUsage)
 			freeMemory := int64(hs.Summary.Hardware.MemorySize) - (int64(hs.Summary.QuickStats.OverallMemoryUsage) * 1024 * 1024)
@@ -150,6 +311,9 @@ func main() {
 			fmt.Fprintf(tw, "%s\t", (units.ByteSize(hs.Summary.QuickStats.OverallMemoryUsage))*1024*1024)
 			fmt.Fprintf(tw, "%s\t", units.ByteSize(hs.Summary.Hardware.MemorySize))
 			fmt.Fprintf(tw, "%d\t", freeMemory)
+			for _, field := range hostFields {
+				fmt.Fprintf(tw, "%s\t", customValue(hs.CustomValue, field.Key))
+			}
 			fmt.Fprintf(tw, "\n")
 		}
 
@@ -191,6 +355,9 @@ func main() {
 		fmt.Fprintf(tw2, "Name:\tType:\tCapacity:\tFree:\n")
 
 		for _, ds := range dss {
+			if !filter.keep(ds.Reference()) {
+				continue
+			}
 			fmt.Fprintf(tw2, "%s\t", ds.Summary.Name)
 			fmt.Fprintf(tw2, "%s\t", ds.Summary.Type)
 			fmt.Fprintf(tw2, "%s\t", units.ByteSize(ds.Summary.Capacity))
@@ -218,13 +385,19 @@ func main() {
 		//
 
 		var vms []mo.VirtualMachine
-		err = v3.Retrieve(ctx, []string{"VirtualMachine"}, []string{"summary"}, &vms)
+		err = v3.Retrieve(ctx, []string{"VirtualMachine"}, []string{"summary", "customValue"}, &vms)
 
 		if err != nil {
 			fmt.Printf("Unable to retrieve VM information: error %s", err)
 			return
 		}
 
+		vmFields, err := customFields(ctx, c, "VirtualMachine")
+		if err != nil {
+			fmt.Printf("Unable to retrieve custom attribute definitions: error %s", err)
+			return
+		}
+
 		//
 		// Print summary per vm (see also: govc/vm/info.go)
 		//
@@ -232,11 +405,22 @@ func main() {
 		tw3 := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
 		fmt.Printf("\n*** VM Information ***\n")
 		fmt.Printf("-----------------------\n\n")
-		fmt.Fprintf(tw3, "Name:\tGuest Full Name:\t\n")
+		fmt.Fprintf(tw3, "Name:\tGuest Full Name:\t")
+		for _, field := range vmFields {
+			fmt.Fprintf(tw3, "%s:\t", field.Name)
+		}
+		fmt.Fprintf(tw3, "\n")
 
 		for _, vm := range vms {
+			if !filter.keep(vm.Reference()) {
+				continue
+			}
 			fmt.Fprintf(tw3, "%s:\t", vm.Summary.Config.Name)
-			fmt.Fprintf(tw3, "%s\t\n", vm.Summary.Config.GuestFullName)
+			fmt.Fprintf(tw3, "%s\t", vm.Summary.Config.GuestFullName)
+			for _, field := range vmFields {
+				fmt.Fprintf(tw3, "%s\t", customValue(vm.CustomValue, field.Key))
+			}
+			fmt.Fprintf(tw3, "\n")
 		}
 
 		fmt.Fprintf(tw3, "\n")
@@ -0,0 +1,476 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and work out how many more VMs of a given size fit in each cluster
+//
+//			  -cpu 4 -mem 16GB -disk 100GB -gpu 1     the size of the VM to plan for
+//			  -cpu-ratio 4 -mem-ratio 1.0             vCPU per physical core and memory overcommit allowed
+//			  -failures 1                             host failures to keep capacity for (N+1 by default)
+//			  -ds-reserve 10                          % of each datastore to keep free
+//
+//			Capacity is counted on the connected hosts that are not in maintenance mode, less the VMs
+//			powered on there. The largest -failures hosts are held back, or the HA admission control
+//			reservation when that is higher (an HA failover level policy raises -failures, dedicated
+//			failover hosts are left out). The answer is the lowest of the CPU, memory, GPU and datastore
+//			limits, and never more than the sum of what fits on each host, since a VM cannot be split
+//			across hosts.
+//
+//			-gpu counts whole GPUs, as given to a VM by passthrough or a full-device vGPU profile. Only the
+//			direct and sharedDirect (vGPU) graphics devices of a host count - basic (onboard VGA) and
+//			shared (vSGA) devices do not. A vGPU device that already runs a VM counts as used, since vGPU
+//			VMs on one device share it by profile - how many more fractional vGPU VMs it could take depends
+//			on the profile and is not planned for.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+//
+// Size is the VM to plan for, Policy how far the cluster may be filled
+//
+
+type Size struct {
+	CPU  int64
+	Mem  units.ByteSize
+	Disk units.ByteSize
+	GPU  int64
+}
+
+type Policy struct {
+	CPURatio  float64
+	MemRatio  float64
+	Failures  int
+	DSReserve float64
+}
+
+//
+// Host is the capacity of one host after overcommit, and what its powered on VMs already take of it
+//
+
+type Host struct {
+	Name    string
+	CPU     int64 // vCPU
+	Mem     int64 // bytes
+	GPU     int64
+	UsedCPU int64
+	UsedMem int64
+	UsedGPU int64
+}
+
+// fit is the number of VMs of the given size that fit in what is left of the host
+func (h Host) fit(size Size) int64 {
+	n := (h.CPU - h.UsedCPU) / size.CPU
+	if m := (h.Mem - h.UsedMem) / int64(size.Mem); m < n {
+		n = m
+	}
+	if size.GPU > 0 {
+		if g := (h.GPU - h.UsedGPU) / size.GPU; g < n {
+			n = g
+		}
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+//
+// Headroom is the result for a cluster - the VMs that fit by each resource, and the overall answer
+//
+
+type Headroom struct {
+	Cluster   string
+	Hosts     int
+	Reserve   string
+	CPU       Line
+	Mem       Line
+	GPU       Line
+	Disk      Line
+	PerHost   int64
+	Fit       int64
+	LimitedBy string
+}
+
+type Line struct {
+	Capacity int64
+	Used     int64
+	Reserved int64
+	Fit      int64
+}
+
+func (l Line) free() int64 {
+	if f := l.Capacity - l.Used - l.Reserved; f > 0 {
+		return f
+	}
+	return 0
+}
+
+// largest sums the n largest values
+func largest(values []int64, n int) int64 {
+	sorted := append([]int64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+	var sum int64
+	for i := 0; i < n && i < len(sorted); i++ {
+		sum += sorted[i]
+	}
+	return sum
+}
+
+//
+// plan works out the headroom of one cluster. HA admission control can reserve a percentage of the cluster
+// or a number of host failures; whichever reserves more - that or -failures hosts - is held back.
+//
+
+func plan(cluster mo.ClusterComputeResource, hosts []Host, datastores []mo.Datastore, size Size, policy Policy) Headroom {
+	h := Headroom{Cluster: cluster.Name, Hosts: len(hosts)}
+
+	failures := policy.Failures
+	cpuPct, memPct := int32(0), int32(0)
+
+	if config, ok := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+		das := config.DasConfig
+		if das.Enabled != nil && *das.Enabled && das.AdmissionControlEnabled != nil && *das.AdmissionControlEnabled {
+			switch p := das.AdmissionControlPolicy.(type) {
+			case *types.ClusterFailoverLevelAdmissionControlPolicy:
+				if int(p.FailoverLevel) > failures {
+					failures = int(p.FailoverLevel)
+				}
+			case *types.ClusterFailoverResourcesAdmissionControlPolicy:
+				cpuPct, memPct = p.CpuFailoverResourcesPercent, p.MemoryFailoverResourcesPercent
+			}
+		}
+	}
+
+	var cpus, mems, gpus []int64
+	for _, host := range hosts {
+		h.CPU.Capacity += host.CPU
+		h.CPU.Used += host.UsedCPU
+		h.Mem.Capacity += host.Mem
+		h.Mem.Used += host.UsedMem
+		h.GPU.Capacity += host.GPU
+		h.GPU.Used += host.UsedGPU
+		cpus = append(cpus, host.CPU)
+		mems = append(mems, host.Mem)
+		gpus = append(gpus, host.GPU)
+		h.PerHost += host.fit(size)
+	}
+
+	h.CPU.Reserved = largest(cpus, failures)
+	h.Mem.Reserved = largest(mems, failures)
+	h.GPU.Reserved = largest(gpus, failures)
+	h.Reserve = fmt.Sprintf("%d host(s)", failures)
+
+	if r := h.CPU.Capacity * int64(cpuPct) / 100; r > h.CPU.Reserved {
+		h.CPU.Reserved = r
+		h.Reserve = fmt.Sprintf("HA %d%% CPU / %d%% memory", cpuPct, memPct)
+	}
+	if r := h.Mem.Capacity * int64(memPct) / 100; r > h.Mem.Reserved {
+		h.Mem.Reserved = r
+		h.Reserve = fmt.Sprintf("HA %d%% CPU / %d%% memory", cpuPct, memPct)
+	}
+
+	h.CPU.Fit = h.CPU.free() / size.CPU
+	h.Mem.Fit = h.Mem.free() / int64(size.Mem)
+
+	// Every datastore the cluster can use, less what is kept free on each
+
+	for _, ds := range datastores {
+		if !ds.Summary.Accessible {
+			continue
+		}
+		reserve := int64(float64(ds.Summary.Capacity) * policy.DSReserve / 100)
+		h.Disk.Capacity += ds.Summary.Capacity
+		h.Disk.Used += ds.Summary.Capacity - ds.Summary.FreeSpace
+		h.Disk.Reserved += reserve
+		if free := ds.Summary.FreeSpace - reserve; free > 0 && size.Disk > 0 {
+			h.Disk.Fit += free / int64(size.Disk)
+		}
+	}
+
+	limits := []struct {
+		name string
+		fit  int64
+		use  bool
+	}{
+		{"CPU", h.CPU.Fit, true},
+		{"memory", h.Mem.Fit, true},
+		{"GPU", 0, size.GPU > 0},
+		{"datastore space", h.Disk.Fit, size.Disk > 0},
+		{"host fragmentation", h.PerHost, true},
+	}
+
+	if size.GPU > 0 {
+		h.GPU.Fit = h.GPU.free() / size.GPU
+		limits[2].fit = h.GPU.Fit
+	}
+
+	h.Fit = -1
+	for _, l := range limits {
+		if l.use && (h.Fit < 0 || l.fit < h.Fit) {
+			h.Fit = l.fit
+			h.LimitedBy = l.name
+		}
+	}
+
+	return h
+}
+
+func printHeadroom(h Headroom, size Size) {
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+
+	fmt.Printf("\n*** Cluster %s ***\n\n", h.Cluster)
+	fmt.Printf("%d usable host(s), holding back %s\n\n", h.Hosts, h.Reserve)
+
+	fmt.Fprintf(tw, "Resource\tCapacity\tAllocated\tReserved\tFree\tVMs Fit\n")
+	fmt.Fprintf(tw, "CPU\t%d vCPU\t%d vCPU\t%d vCPU\t%d vCPU\t%d\n", h.CPU.Capacity, h.CPU.Used, h.CPU.Reserved, h.CPU.free(), h.CPU.Fit)
+	fmt.Fprintf(tw, "Memory\t%s\t%s\t%s\t%s\t%d\n", units.ByteSize(h.Mem.Capacity), units.ByteSize(h.Mem.Used),
+		units.ByteSize(h.Mem.Reserved), units.ByteSize(h.Mem.free()), h.Mem.Fit)
+	if size.GPU > 0 {
+		fmt.Fprintf(tw, "GPU\t%d\t%d\t%d\t%d\t%d\n", h.GPU.Capacity, h.GPU.Used, h.GPU.Reserved, h.GPU.free(), h.GPU.Fit)
+	}
+	if size.Disk > 0 {
+		fmt.Fprintf(tw, "Datastore\t%s\t%s\t%s\t%s\t%d\n", units.ByteSize(h.Disk.Capacity), units.ByteSize(h.Disk.Used),
+			units.ByteSize(h.Disk.Reserved), units.ByteSize(h.Disk.free()), h.Disk.Fit)
+	}
+	fmt.Fprintf(tw, "Per host\t\t\t\t\t%d\n", h.PerHost)
+	_ = tw.Flush()
+
+	fmt.Printf("\nHeadroom: %d more VM(s) of %d vCPU / %s", h.Fit, size.CPU, size.Mem)
+	if size.Disk > 0 {
+		fmt.Printf(" / %s disk", size.Disk)
+	}
+	if size.GPU > 0 {
+		fmt.Printf(" / %d GPU", size.GPU)
+	}
+	fmt.Printf(", limited by %s\n", h.LimitedBy)
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var pattern string
+	var size Size
+	var policy Policy
+
+	size.Mem = 4 * units.GB
+	size.Disk = 40 * units.GB
+
+	flag.StringVar(&pattern, "cluster", "*", "only clusters whose name matches this pattern")
+	flag.Int64Var(&size.CPU, "cpu", 2, "vCPUs of the VM to plan for")
+	flag.Var(&size.Mem, "mem", "memory of the VM to plan for")
+	flag.Var(&size.Disk, "disk", "disk space of the VM to plan for (0 to ignore datastores)")
+	flag.Int64Var(&size.GPU, "gpu", 0, "whole GPUs (passthrough or full-device vGPU) of the VM to plan for")
+	flag.Float64Var(&policy.CPURatio, "cpu-ratio", 4, "vCPUs allowed per physical core")
+	flag.Float64Var(&policy.MemRatio, "mem-ratio", 1, "memory overcommit allowed (1.0 is none)")
+	flag.IntVar(&policy.Failures, "failures", 1, "host failures to keep capacity for")
+	flag.Float64Var(&policy.DSReserve, "ds-reserve", 10, "percent of each datastore to keep free")
+	flag.Parse()
+
+	if size.CPU < 1 || size.Mem < 1 || size.GPU < 0 || policy.CPURatio <= 0 || policy.MemRatio <= 0 || policy.Failures < 0 {
+		fmt.Printf("-cpu and -mem must be positive, and -gpu, -failures and the ratios not negative\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := view.NewManager(c)
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"ClusterComputeResource", "VirtualMachine"}, true)
+	if err != nil {
+		fmt.Printf("Could not create container view, error %v\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	var clusters []mo.ClusterComputeResource
+	err = v.RetrieveWithFilter(ctx, []string{"ClusterComputeResource"}, []string{"name", "host", "datastore", "configurationEx"}, &clusters, property.Filter{"name": pattern})
+	if err != nil {
+		fmt.Printf("Could not get list of clusters, error %v\n", err)
+		return
+	}
+
+	if len(clusters) == 0 {
+		fmt.Printf("No clusters match %s\n", pattern)
+		return
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	//
+	// The VM allocations are counted per host, from the powered on VMs only
+	//
+
+	var vms []mo.VirtualMachine
+	err = v.Retrieve(ctx, []string{"VirtualMachine"}, []string{"config.hardware", "runtime.host", "runtime.powerState"}, &vms)
+	if err != nil {
+		fmt.Printf("Could not get VMs, error %v\n", err)
+		return
+	}
+
+	usedCPU := make(map[types.ManagedObjectReference]int64)
+	usedMem := make(map[types.ManagedObjectReference]int64)
+	for _, vm := range vms {
+		if vm.Config == nil || vm.Runtime.Host == nil || vm.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+			continue
+		}
+		usedCPU[*vm.Runtime.Host] += int64(vm.Config.Hardware.NumCPU)
+		usedMem[*vm.Runtime.Host] += int64(vm.Config.Hardware.MemoryMB) << 20
+	}
+
+	pc := property.DefaultCollector(c)
+	var results []Headroom
+
+	for _, cluster := range clusters {
+
+		// Dedicated HA failover hosts take no VMs, so they are not counted
+
+		failover := make(map[types.ManagedObjectReference]bool)
+		if config, ok := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+			if p, ok := config.DasConfig.AdmissionControlPolicy.(*types.ClusterFailoverHostAdmissionControlPolicy); ok {
+				for _, ref := range p.FailoverHosts {
+					failover[ref] = true
+				}
+			}
+		}
+
+		var hostList []mo.HostSystem
+		if len(cluster.Host) > 0 {
+			err = pc.Retrieve(ctx, cluster.Host, []string{"name", "runtime", "summary.hardware", "config.graphicsInfo"}, &hostList)
+			if err != nil {
+				fmt.Printf("Could not retrieve hosts of %s, error %v\n", cluster.Name, err)
+				return
+			}
+		}
+
+		var hosts []Host
+		for _, hs := range hostList {
+			if hs.Runtime.ConnectionState != types.HostSystemConnectionStateConnected || hs.Runtime.InMaintenanceMode || failover[hs.Self] {
+				continue
+			}
+
+			host := Host{
+				Name:    hs.Name,
+				CPU:     int64(float64(hs.Summary.Hardware.NumCpuCores) * policy.CPURatio),
+				Mem:     int64(float64(hs.Summary.Hardware.MemorySize) * policy.MemRatio),
+				UsedCPU: usedCPU[hs.Self],
+				UsedMem: usedMem[hs.Self],
+			}
+
+			if hs.Config != nil {
+				for _, g := range hs.Config.GraphicsInfo {
+					switch types.HostGraphicsInfoGraphicsType(g.GraphicsType) {
+					case types.HostGraphicsInfoGraphicsTypeDirect, types.HostGraphicsInfoGraphicsTypeSharedDirect:
+						host.GPU++
+						if len(g.Vm) > 0 {
+							host.UsedGPU++
+						}
+					}
+				}
+			}
+
+			hosts = append(hosts, host)
+		}
+
+		var datastores []mo.Datastore
+		if size.Disk > 0 && len(cluster.Datastore) > 0 {
+			err = pc.Retrieve(ctx, cluster.Datastore, []string{"name", "summary"}, &datastores)
+			if err != nil {
+				fmt.Printf("Could not retrieve datastores of %s, error %v\n", cluster.Name, err)
+				return
+			}
+		}
+
+		h := plan(cluster, hosts, datastores, size, policy)
+		printHeadroom(h, size)
+		results = append(results, h)
+	}
+
+	if len(results) < 2 {
+		return
+	}
+
+	fmt.Printf("\nSummary\n-------\n")
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Cluster\tHosts\tVMs Fit\tLimited By\n")
+	var total int64
+	for _, h := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", h.Cluster, h.Hosts, h.Fit, h.LimitedBy)
+		total += h.Fit
+	}
+	fmt.Fprintf(tw, "Total\t\t%d\t\n", total)
+	_ = tw.Flush()
+}
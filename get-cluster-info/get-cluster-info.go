@@ -0,0 +1,316 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and report the configuration and capacity of every cluster - host count, total and
+//			effective CPU/memory, DRS automation level and migration threshold, HA host monitoring,
+//			admission control and failover settings, EVC mode and whether vSAN is enabled
+//
+//			-cluster limits the report to the clusters whose name matches a pattern.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// enabled prints an optional flag of the cluster configuration, which is unset when the feature was never configured
+func enabled(b *bool) string {
+	if b != nil && *b {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+//
+// threshold turns the DRS vmotionRate into the migration threshold shown in the vSphere Client. The two scales
+// run in opposite directions: vmotionRate 1 is the most aggressive threshold (client level 5) and vmotionRate 5
+// the most conservative (client level 1), so the client level is 6 - vmotionRate.
+//
+
+func threshold(rate int32) string {
+	if rate < 1 || rate > 5 {
+		return "-"
+	}
+	level := 6 - rate
+	switch level {
+	case 1:
+		return "1 (conservative)"
+	case 5:
+		return "5 (aggressive)"
+	}
+	return fmt.Sprintf("%d", level)
+}
+
+//
+// admissionControl describes the HA admission control policy, plus what it currently allows where vCenter
+// reports it
+//
+
+func admissionControl(das *types.ClusterDasConfigInfo, info types.BaseClusterDasAdmissionControlInfo, names map[types.ManagedObjectReference]string) string {
+	if das.AdmissionControlEnabled == nil || !*das.AdmissionControlEnabled {
+		return "disabled"
+	}
+
+	var policy string
+
+	switch p := das.AdmissionControlPolicy.(type) {
+	case *types.ClusterFailoverLevelAdmissionControlPolicy:
+		policy = fmt.Sprintf("tolerate %d host failure(s), slot policy", p.FailoverLevel)
+	case *types.ClusterFailoverResourcesAdmissionControlPolicy:
+		policy = fmt.Sprintf("reserve %d%% CPU / %d%% memory", p.CpuFailoverResourcesPercent, p.MemoryFailoverResourcesPercent)
+		if p.AutoComputePercentages != nil && *p.AutoComputePercentages {
+			policy = fmt.Sprintf("reserve capacity for %d host failure(s) (%d%% CPU / %d%% memory)",
+				p.FailoverLevel, p.CpuFailoverResourcesPercent, p.MemoryFailoverResourcesPercent)
+		}
+	case *types.ClusterFailoverHostAdmissionControlPolicy:
+		var hosts []string
+		for _, h := range p.FailoverHosts {
+			hosts = append(hosts, names[h])
+		}
+		policy = fmt.Sprintf("dedicated failover hosts %s", strings.Join(hosts, ", "))
+	default:
+		policy = fmt.Sprintf("tolerate %d host failure(s)", das.FailoverLevel)
+	}
+
+	switch i := info.(type) {
+	case *types.ClusterFailoverResourcesAdmissionControlInfo:
+		policy += fmt.Sprintf(", currently %d%% CPU / %d%% memory available for failover",
+			i.CurrentCpuFailoverResourcesPercent, i.CurrentMemoryFailoverResourcesPercent)
+	case *types.ClusterFailoverLevelAdmissionControlInfo:
+		policy += fmt.Sprintf(", currently %d host failure(s) tolerated", i.CurrentFailoverLevel)
+	}
+
+	return policy
+}
+
+func printCluster(cluster mo.ClusterComputeResource, path string, hosts []mo.HostSystem, names map[types.ManagedObjectReference]string) {
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+
+	summary, _ := cluster.Summary.(*types.ClusterComputeResourceSummary)
+	config, _ := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx)
+
+	fmt.Printf("\n*** Cluster %s ***\n\n", cluster.Name)
+	fmt.Fprintf(tw, "Path:\t%s\n", path)
+	fmt.Fprintf(tw, "Overall Status:\t%s\n", cluster.OverallStatus)
+
+	connected, maintenance := 0, 0
+	for _, h := range hosts {
+		if h.Runtime.ConnectionState == types.HostSystemConnectionStateConnected {
+			connected++
+		}
+		if h.Runtime.InMaintenanceMode {
+			maintenance++
+		}
+	}
+
+	if summary != nil {
+		fmt.Fprintf(tw, "Hosts:\t%d (%d effective, %d connected, %d in maintenance mode)\n", summary.NumHosts, summary.NumEffectiveHosts, connected, maintenance)
+	} else {
+		fmt.Fprintf(tw, "Hosts:\t%d (%d connected, %d in maintenance mode)\n", len(hosts), connected, maintenance)
+	}
+	_ = tw.Flush()
+
+	//
+	// Total capacity is the sum of the hosts, effective capacity what is left for VMs once the hosts that are
+	// not available and the virtualization overhead are taken out
+	//
+
+	if summary != nil {
+		fmt.Printf("\nResources\n---------\n")
+		fmt.Fprintf(tw, "CPU:\t%.1f GHz total, %.1f GHz effective (%d cores, %d threads)\n",
+			float64(summary.TotalCpu)/1000, float64(summary.EffectiveCpu)/1000, summary.NumCpuCores, summary.NumCpuThreads)
+		fmt.Fprintf(tw, "Memory:\t%.1f GB total, %.1f GB effective\n",
+			float64(summary.TotalMemory)/(1<<30), float64(summary.EffectiveMemory)/(1<<10))
+		_ = tw.Flush()
+	}
+
+	if config == nil {
+		return
+	}
+
+	fmt.Printf("\nDRS\n---\n")
+	drs := config.DrsConfig
+	fmt.Fprintf(tw, "DRS:\t%s\n", enabled(drs.Enabled))
+	if drs.Enabled != nil && *drs.Enabled {
+		fmt.Fprintf(tw, "Automation Level:\t%s\n", drs.DefaultVmBehavior)
+		fmt.Fprintf(tw, "Migration Threshold:\t%s\n", threshold(drs.VmotionRate))
+		fmt.Fprintf(tw, "VM Overrides:\t%s\n", enabled(drs.EnableVmBehaviorOverrides))
+	}
+	if config.DpmConfigInfo != nil {
+		fmt.Fprintf(tw, "DPM:\t%s\n", enabled(config.DpmConfigInfo.Enabled))
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\nHA\n--\n")
+	das := config.DasConfig
+	fmt.Fprintf(tw, "HA:\t%s\n", enabled(das.Enabled))
+	if das.Enabled != nil && *das.Enabled {
+		var info types.BaseClusterDasAdmissionControlInfo
+		if summary != nil {
+			info = summary.AdmissionControlInfo
+		}
+		fmt.Fprintf(tw, "Host Monitoring:\t%s\n", das.HostMonitoring)
+		fmt.Fprintf(tw, "VM Monitoring:\t%s\n", das.VmMonitoring)
+		fmt.Fprintf(tw, "Admission Control:\t%s\n", admissionControl(&das, info, names))
+		if s := das.DefaultVmSettings; s != nil {
+			fmt.Fprintf(tw, "Restart Priority:\t%s\n", s.RestartPriority)
+			fmt.Fprintf(tw, "Isolation Response:\t%s\n", s.IsolationResponse)
+		}
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\nEVC / vSAN\n----------\n")
+	evc := "disabled"
+	if summary != nil && summary.CurrentEVCModeKey != "" {
+		evc = summary.CurrentEVCModeKey
+	}
+	fmt.Fprintf(tw, "EVC Mode:\t%s\n", evc)
+	vsan := "disabled"
+	if config.VsanConfigInfo != nil {
+		vsan = enabled(config.VsanConfigInfo.Enabled)
+	}
+	fmt.Fprintf(tw, "vSAN:\t%s\n", vsan)
+	_ = tw.Flush()
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var pattern string
+
+	flag.StringVar(&pattern, "cluster", "*", "only clusters whose name matches this pattern")
+	flag.Parse()
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := view.NewManager(c)
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"ClusterComputeResource"}, true)
+	if err != nil {
+		fmt.Printf("Could not create container view, error %v\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	var clusters []mo.ClusterComputeResource
+	err = v.RetrieveWithFilter(ctx, []string{"ClusterComputeResource"}, []string{"name", "host", "summary", "configurationEx", "overallStatus"}, &clusters, property.Filter{"name": pattern})
+	if err != nil {
+		fmt.Printf("Could not get list of clusters, error %v\n", err)
+		return
+	}
+
+	if len(clusters) == 0 {
+		fmt.Printf("No clusters match %s\n", pattern)
+		return
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	//
+	// The state and names of every clustered host in one call - the names are needed for dedicated HA
+	// failover hosts
+	//
+
+	var refs []types.ManagedObjectReference
+	for _, cluster := range clusters {
+		refs = append(refs, cluster.Host...)
+	}
+
+	hosts := make(map[types.ManagedObjectReference]mo.HostSystem)
+	names := make(map[types.ManagedObjectReference]string)
+
+	if len(refs) > 0 {
+		var list []mo.HostSystem
+		err = property.DefaultCollector(c).Retrieve(ctx, refs, []string{"name", "runtime.connectionState", "runtime.inMaintenanceMode"}, &list)
+		if err != nil {
+			fmt.Printf("Could not retrieve hosts, error %v\n", err)
+			return
+		}
+		for _, h := range list {
+			hosts[h.Self] = h
+			names[h.Self] = h.Name
+		}
+	}
+
+	for _, cluster := range clusters {
+		var members []mo.HostSystem
+		for _, ref := range cluster.Host {
+			members = append(members, hosts[ref])
+		}
+
+		path, _ := find.InventoryPath(ctx, c, cluster.Self)
+		printCluster(cluster, path, members, names)
+	}
+}
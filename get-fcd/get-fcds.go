@@ -20,12 +20,15 @@ import (
 	"net/url"
 	"os"
 	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
@@ -43,6 +46,85 @@ func (n dsByName) Len() int           { return len(n) }
 func (n dsByName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
 func (n dsByName) Less(i, j int) bool { return n[i].Name < n[j].Name }
 
+//
+// tagFilter holds the -tag category:name values. With -tag-match all (the default) an object must carry every
+// tag, with -tag-match any it needs at least one of them.
+//
+
+type tagFilter struct {
+	tags    []string
+	match   string
+	objects map[types.ManagedObjectReference]bool
+}
+
+func (f *tagFilter) String() string { return strings.Join(f.tags, ",") }
+
+func (f *tagFilter) Set(value string) error {
+	if i := strings.Index(value, ":"); i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("want category:name, got %q", value)
+	}
+	f.tags = append(f.tags, value)
+	return nil
+}
+
+//
+// resolve finds the objects that pass the filter. Tags live behind the vAPI (REST) endpoint, which needs its own
+// login, and GetAttachedObjectsOnTags returns the objects for all of the tags in one call.
+//
+
+func (f *tagFilter) resolve(ctx context.Context, c *vim25.Client, user *url.Userinfo) error {
+	if len(f.tags) == 0 {
+		return nil
+	}
+	if f.match != "all" && f.match != "any" {
+		return fmt.Errorf("-tag-match must be \"all\" or \"any\", not %q", f.match)
+	}
+
+	rc := rest.NewClient(c)
+	if err := rc.Login(ctx, user); err != nil {
+		return fmt.Errorf("could not log in to the tagging service: %s", err)
+	}
+	defer rc.Logout(ctx)
+
+	m := tags.NewManager(rc)
+
+	var ids []string
+	for _, name := range f.tags {
+		i := strings.Index(name, ":")
+		tag, err := m.GetTagForCategory(ctx, name[i+1:], name[:i])
+		if err != nil {
+			return fmt.Errorf("could not find tag %s: %s", name, err)
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	attached, err := m.GetAttachedObjectsOnTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("could not get the objects tagged %s: %s", f, err)
+	}
+
+	count := make(map[types.ManagedObjectReference]int)
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			count[obj.Reference()]++
+		}
+	}
+
+	f.objects = make(map[types.ManagedObjectReference]bool)
+	for ref, n := range count {
+		if f.match == "any" || n == len(ids) {
+			f.objects[ref] = true
+		}
+	}
+
+	return nil
+}
+
+// keep reports whether an object passes the filter - everything passes when no -tag was given
+func (f *tagFilter) keep(ref types.ManagedObjectReference) bool {
+	return len(f.tags) == 0 || f.objects[ref]
+}
+
 func main() {
 
 	//
@@ -88,6 +170,16 @@ func main() {
 	var insecure bool
 	flag.BoolVar(&insecure, "insecure", true, "ignore any vCenter TLS cert validation error")
 
+	//
+	// FCDs are not managed objects, so they cannot carry vSphere tags themselves. -tag category:name
+	// (repeatable) scopes the report to the FCDs on tagged datastores instead.
+	//
+
+	var filter tagFilter
+	flag.Var(&filter, "tag", "only report FCDs on datastores with this category:name tag (repeat for more than one tag)")
+	flag.StringVar(&filter.match, "tag-match", "all", "with more than one -tag, datastores must have \"all\" of the tags or \"any\" of them")
+	flag.Parse()
+
 	//
 	// Explanation of context:
 	//
@@ -149,6 +241,12 @@ func main() {
 		fmt.Println("")
 	}
 
+	err = filter.resolve(ctx, c.Client, u.User)
+	if err != nil {
+		fmt.Println("Unable to apply tag filter, error: ", err)
+		return
+	}
+
 	//
 	// -- "find" implements inventory listing and searching.
 	// -- https://gowalker.org/github.com/vmware/govmomi/find
@@ -211,7 +309,16 @@ func main() {
 
 		var refs []types.ManagedObjectReference
 		for _, ds := range dss {
-			refs = append(refs, ds.Reference())
+			if filter.keep(ds.Reference()) {
+				refs = append(refs, ds.Reference())
+			}
+		}
+
+		if len(refs) == 0 {
+			if len(filter.tags) > 0 {
+				fmt.Println("No datastores carry the tag(s): ", filter.String())
+			}
+			return
 		}
 
 		//
@@ -0,0 +1,397 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and retrieve the physical NIC and VMkernel adapter inventory of each host
+//
+//			Physical NICs are listed with link speed, duplex, driver, MAC address and the standard
+//			vSwitch or DVS uplink they are assigned to. VMkernel adapters are listed with IP address,
+//			MTU, the port group they live on and the services (vMotion, vSAN, management, ...)
+//			enabled on them. Everything comes from HostSystem config.network and
+//			config.virtualNicManagerInfo.
+//
+//			Output is a table by default, or JSON with -json
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// PnicInfo describes one physical NIC on a host
+type PnicInfo struct {
+	Host        string `json:"host"`
+	Device      string `json:"device"`
+	Driver      string `json:"driver"`
+	Pci         string `json:"pci"`
+	Mac         string `json:"mac"`
+	LinkUp      bool   `json:"linkUp"`
+	SpeedMb     int32  `json:"speedMb,omitempty"`
+	Duplex      string `json:"duplex,omitempty"`
+	Switch      string `json:"switch,omitempty"`
+	Uplink      string `json:"uplink,omitempty"`
+	Distributed bool   `json:"distributed"`
+}
+
+// VmknicInfo describes one VMkernel adapter on a host
+type VmknicInfo struct {
+	Host      string   `json:"host"`
+	Device    string   `json:"device"`
+	IPAddress string   `json:"ipAddress,omitempty"`
+	Netmask   string   `json:"netmask,omitempty"`
+	Dhcp      bool     `json:"dhcp"`
+	Mac       string   `json:"mac"`
+	Mtu       int32    `json:"mtu"`
+	Portgroup string   `json:"portgroup"`
+	NetStack  string   `json:"netStack,omitempty"`
+	Services  []string `json:"services,omitempty"`
+}
+
+// HostNetworkReport is the top level document written with -json
+type HostNetworkReport struct {
+	Pnics   []PnicInfo   `json:"pnics"`
+	Vmknics []VmknicInfo `json:"vmknics"`
+}
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	//
+	// Create a vSphere/vCenter client
+	//
+	//    The govmomi client requires a URL object, u, not just a string representation of the vCenter URL.
+	//
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Fprintf(os.Stderr, "could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Log in successful\n")
+
+	return c, nil
+}
+
+//
+// hostNetworking flattens the network config of one host into pNIC and vmknic rows.
+// dvpgNames maps distributed port group keys to names, as a vmknic on a DVS only records the key.
+//
+
+func hostNetworking(hs mo.HostSystem, dvpgNames map[string]string) ([]PnicInfo, []VmknicInfo) {
+	var pnics []PnicInfo
+	var vmknics []VmknicInfo
+
+	if hs.Config == nil || hs.Config.Network == nil {
+		return pnics, vmknics
+	}
+
+	network := hs.Config.Network
+
+	//
+	// Work out which switch each pNIC is an uplink of. Standard vSwitches list pNIC keys,
+	// proxy switches (the host side of a DVS) list pNIC devices against uplink port keys.
+	//
+
+	type assignment struct {
+		sw          string
+		uplink      string
+		distributed bool
+	}
+
+	uplinks := make(map[string]assignment)
+
+	for _, vs := range network.Vswitch {
+		for _, key := range vs.Pnic {
+			uplinks[key] = assignment{sw: vs.Name}
+		}
+	}
+
+	for _, ps := range network.ProxySwitch {
+		portNames := make(map[string]string)
+		for _, kv := range ps.UplinkPort {
+			portNames[kv.Key] = kv.Value
+		}
+
+		backing, ok := ps.Spec.Backing.(*types.DistributedVirtualSwitchHostMemberPnicBacking)
+		if !ok {
+			continue
+		}
+		for _, spec := range backing.PnicSpec {
+			uplinks[spec.PnicDevice] = assignment{sw: ps.DvsName, uplink: portNames[spec.UplinkPortKey], distributed: true}
+		}
+	}
+
+	for _, pnic := range network.Pnic {
+		info := PnicInfo{
+			Host:   hs.Name,
+			Device: pnic.Device,
+			Driver: pnic.Driver,
+			Pci:    pnic.Pci,
+			Mac:    pnic.Mac,
+		}
+
+		// No link speed means the link is down
+
+		if pnic.LinkSpeed != nil {
+			info.LinkUp = true
+			info.SpeedMb = pnic.LinkSpeed.SpeedMb
+			if pnic.LinkSpeed.Duplex {
+				info.Duplex = "full"
+			} else {
+				info.Duplex = "half"
+			}
+		}
+
+		a, ok := uplinks[pnic.Key]
+		if !ok {
+			a = uplinks[pnic.Device]
+		}
+		info.Switch = a.sw
+		info.Uplink = a.uplink
+		info.Distributed = a.distributed
+
+		pnics = append(pnics, info)
+	}
+
+	//
+	// Services are recorded per service type as a list of selected vmknic keys, so invert that into vmknic -> services
+	//
+
+	services := make(map[string][]string)
+
+	if hs.Config.VirtualNicManagerInfo != nil {
+		for _, nc := range hs.Config.VirtualNicManagerInfo.NetConfig {
+			keyToDevice := make(map[string]string)
+			for _, cand := range nc.CandidateVnic {
+				keyToDevice[cand.Key] = cand.Device
+			}
+			for _, key := range nc.SelectedVnic {
+				if dev, ok := keyToDevice[key]; ok {
+					services[dev] = append(services[dev], nc.NicType)
+				}
+			}
+		}
+	}
+
+	for _, vnic := range network.Vnic {
+		info := VmknicInfo{
+			Host:      hs.Name,
+			Device:    vnic.Device,
+			Mac:       vnic.Spec.Mac,
+			Mtu:       vnic.Spec.Mtu,
+			Portgroup: vnic.Portgroup,
+			NetStack:  vnic.Spec.NetStackInstanceKey,
+			Services:  services[vnic.Device],
+		}
+
+		if ip := vnic.Spec.Ip; ip != nil {
+			info.IPAddress = ip.IpAddress
+			info.Netmask = ip.SubnetMask
+			info.Dhcp = ip.Dhcp
+		}
+
+		if dvp := vnic.Spec.DistributedVirtualPort; dvp != nil && info.Portgroup == "" {
+			info.Portgroup = dvpgNames[dvp.PortgroupKey]
+			if info.Portgroup == "" {
+				info.Portgroup = dvp.PortgroupKey
+			}
+		}
+
+		sort.Strings(info.Services)
+
+		vmknics = append(vmknics, info)
+	}
+
+	return pnics, vmknics
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var jsonOutput bool
+	flag.BoolVar(&jsonOutput, "json", false, "write the report as JSON instead of a table")
+	flag.Parse()
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//
+	// Call the login function
+	//
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := view.NewManager(c)
+
+	//
+	// Create a container view of HostSystem objects and retrieve only the networking part of the host config
+	//
+	// Ref: https://vdc-download.vmware.com/vmwb-repository/dcr-public/b50dcbbf-051d-4204-a3e7-e1b618c1e384/538cf2ec-b34f-4bae-a332-3820ef9e7773/vim.host.NetworkInfo.html
+	//
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"HostSystem"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Host Container View: error %s\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	var hss []mo.HostSystem
+	err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"name", "config.network", "config.virtualNicManagerInfo"}, &hss)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve Host information: error %s\n", err)
+		return
+	}
+
+	//
+	// Distributed port group names, used to label VMkernel adapters connected to a DVS
+	//
+
+	p, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualPortgroup"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create DVS PG Container View: error %s\n", err)
+		return
+	}
+
+	defer p.Destroy(ctx)
+
+	var vdspg []mo.DistributedVirtualPortgroup
+	err = p.Retrieve(ctx, []string{"DistributedVirtualPortgroup"}, []string{"name", "key"}, &vdspg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve DVS PG information: error %s\n", err)
+		return
+	}
+
+	dvpgNames := make(map[string]string)
+	for _, pg := range vdspg {
+		dvpgNames[pg.Key] = pg.Name
+	}
+
+	sort.Slice(hss, func(i, j int) bool { return hss[i].Name < hss[j].Name })
+
+	var report HostNetworkReport
+
+	for _, hs := range hss {
+		pnics, vmknics := hostNetworking(hs, dvpgNames)
+		report.Pnics = append(report.Pnics, pnics...)
+		report.Vmknics = append(report.Vmknics, vmknics...)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to encode JSON: error %s\n", err)
+		}
+		return
+	}
+
+	//
+	// -- https://golang.org/pkg/text/tabwriter/#NewWriter
+	//
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 4, ' ', 0)
+
+	fmt.Printf("\n*** Host Physical NIC Information ***\n")
+	fmt.Printf("--------------------------------------\n\n")
+	fmt.Fprintf(tw, "Host\tDevice\tDriver\tMAC Address\tLink\tSpeed(Mb)\tDuplex\tSwitch\tUplink\n")
+	fmt.Fprintf(tw, "----\t------\t------\t--- -------\t----\t---------\t------\t------\t------\n")
+
+	for _, pnic := range report.Pnics {
+		link := "down"
+		if pnic.LinkUp {
+			link = "up"
+		}
+		fmt.Fprintf(tw, "%s\t", pnic.Host)
+		fmt.Fprintf(tw, "%s\t", pnic.Device)
+		fmt.Fprintf(tw, "%s\t", pnic.Driver)
+		fmt.Fprintf(tw, "%s\t", pnic.Mac)
+		fmt.Fprintf(tw, "%s\t", link)
+		fmt.Fprintf(tw, "%d\t", pnic.SpeedMb)
+		fmt.Fprintf(tw, "%s\t", pnic.Duplex)
+		fmt.Fprintf(tw, "%s\t", pnic.Switch)
+		fmt.Fprintf(tw, "%s\n", pnic.Uplink)
+	}
+
+	_ = tw.Flush()
+
+	fmt.Printf("\n*** Host VMkernel Adapter Information ***\n")
+	fmt.Printf("------------------------------------------\n\n")
+	fmt.Fprintf(tw, "Host\tDevice\tIP Address\tNetmask\tMAC Address\tMTU\tPort Group\tTCP/IP Stack\tServices\n")
+	fmt.Fprintf(tw, "----\t------\t-- -------\t-------\t--- -------\t---\t---- -----\t------ -----\t--------\n")
+
+	for _, vmk := range report.Vmknics {
+		ip := vmk.IPAddress
+		if vmk.Dhcp {
+			ip += " (dhcp)"
+		}
+		fmt.Fprintf(tw, "%s\t", vmk.Host)
+		fmt.Fprintf(tw, "%s\t", vmk.Device)
+		fmt.Fprintf(tw, "%s\t", ip)
+		fmt.Fprintf(tw, "%s\t", vmk.Netmask)
+		fmt.Fprintf(tw, "%s\t", vmk.Mac)
+		fmt.Fprintf(tw, "%d\t", vmk.Mtu)
+		fmt.Fprintf(tw, "%s\t", vmk.Portgroup)
+		fmt.Fprintf(tw, "%s\t", vmk.NetStack)
+		fmt.Fprintf(tw, "%s\n", strings.Join(vmk.Services, ","))
+	}
+
+	fmt.Fprintf(tw, "\n")
+
+	_ = tw.Flush()
+}
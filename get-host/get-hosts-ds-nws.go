@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 //	"reflect"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
 	"net/url"
 
@@ -12,11 +15,163 @@ import (
         "github.com/vmware/govmomi/vim25"
         "github.com/vmware/govmomi/vim25/mo"
         "github.com/vmware/govmomi/vim25/soap"
+        "github.com/vmware/govmomi/vim25/types"
         "github.com/vmware/govmomi/session/cache"
+        "github.com/vmware/govmomi/vapi/rest"
+        "github.com/vmware/govmomi/vapi/tags"
 
 )
 
 
+//
+// networkRow is one line of the network report - standard port groups, distributed port groups
+// and opaque (NSX) networks are all reduced to the same columns
+//
+
+type networkRow struct {
+	ref     types.ManagedObjectReference
+	name    string
+	kind    string
+	backing string
+	vlan    string
+	hosts   []string
+	vms     []string
+}
+
+//
+// vlanString renders the VLAN spec of a DVS port setting - a single VLAN ID, trunk ranges or a private VLAN ID
+//
+
+func vlanString(setting types.BaseDVPortSetting) string {
+	ps, ok := setting.(*types.VMwareDVSPortSetting)
+	if !ok || ps == nil {
+		return "-"
+	}
+
+	switch vlan := ps.Vlan.(type) {
+	case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
+		return fmt.Sprintf("%d", vlan.VlanId)
+	case *types.VmwareDistributedVirtualSwitchTrunkVlanSpec:
+		var ranges []string
+		for _, r := range vlan.VlanId {
+			if r.Start == r.End {
+				ranges = append(ranges, fmt.Sprintf("%d", r.Start))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", r.Start, r.End))
+			}
+		}
+		return "trunk " + strings.Join(ranges, ",")
+	case *types.VmwareDistributedVirtualSwitchPvlanSpec:
+		return fmt.Sprintf("pvlan %d", vlan.PvlanId)
+	}
+	return "-"
+}
+
+//
+// joinSet returns the distinct values of a set, sorted and comma separated. A standard port group is defined
+// per host, so its vSwitch and VLAN can differ from host to host.
+//
+
+func joinSet(set map[string]bool) string {
+	if len(set) == 0 {
+		return "-"
+	}
+	var values []string
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func refNames(refs []types.ManagedObjectReference, names map[types.ManagedObjectReference]string) []string {
+	var list []string
+	for _, ref := range refs {
+		list = append(list, names[ref])
+	}
+	sort.Strings(list)
+	return list
+}
+
+//
+// tagFilter holds the -tag category:name values. With -tag-match all (the default) an object must carry every
+// tag, with -tag-match any it needs at least one of them.
+//
+
+type tagFilter struct {
+	tags    []string
+	match   string
+	objects map[types.ManagedObjectReference]bool
+}
+
+func (f *tagFilter) String() string { return strings.Join(f.tags, ",") }
+
+func (f *tagFilter) Set(value string) error {
+	if i := strings.Index(value, ":"); i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("want category:name, got %q", value)
+	}
+	f.tags = append(f.tags, value)
+	return nil
+}
+
+//
+// resolve finds the objects that pass the filter. Tags live behind the vAPI (REST) endpoint, which needs its own
+// login, and GetAttachedObjectsOnTags returns the objects for all of the tags in one call.
+//
+
+func (f *tagFilter) resolve(ctx context.Context, c *vim25.Client, user *url.Userinfo) error {
+	if len(f.tags) == 0 {
+		return nil
+	}
+	if f.match != "all" && f.match != "any" {
+		return fmt.Errorf("-tag-match must be \"all\" or \"any\", not %q", f.match)
+	}
+
+	rc := rest.NewClient(c)
+	if err := rc.Login(ctx, user); err != nil {
+		return fmt.Errorf("could not log in to the tagging service: %s", err)
+	}
+	defer rc.Logout(ctx)
+
+	m := tags.NewManager(rc)
+
+	var ids []string
+	for _, name := range f.tags {
+		i := strings.Index(name, ":")
+		tag, err := m.GetTagForCategory(ctx, name[i+1:], name[:i])
+		if err != nil {
+			return fmt.Errorf("could not find tag %s: %s", name, err)
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	attached, err := m.GetAttachedObjectsOnTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("could not get the objects tagged %s: %s", f, err)
+	}
+
+	count := make(map[types.ManagedObjectReference]int)
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			count[obj.Reference()]++
+		}
+	}
+
+	f.objects = make(map[types.ManagedObjectReference]bool)
+	for ref, n := range count {
+		if f.match == "any" || n == len(ids) {
+			f.objects[ref] = true
+		}
+	}
+
+	return nil
+}
+
+// keep reports whether an object passes the filter - everything passes when no -tag was given
+func (f *tagFilter) keep(ref types.ManagedObjectReference) bool {
+	return len(f.tags) == 0 || f.objects[ref]
+}
+
 func main() {
 
 // We need to get 3 environment variables:
@@ -25,6 +180,15 @@ func main() {
 // GOVMOMI_USERNAME
 // GOVMOMI_PASSWORD
 
+//
+// -tag category:name (repeatable) scopes the report to tagged hosts, datastores and networks
+//
+
+        var filter tagFilter
+        flag.Var(&filter, "tag", "only report objects with this category:name tag (repeat for more than one tag)")
+        flag.StringVar(&filter.match, "tag-match", "all", "with more than one -tag, objects must have \"all\" of the tags or \"any\" of them")
+        flag.Parse()
+
         vc := os.Getenv ("GOVMOMI_URL")
         user := os.Getenv ("GOVMOMI_USERNAME")
         pwd := os.Getenv ("GOVMOMI_PASSWORD")
@@ -80,6 +244,12 @@ func main() {
         } else {
                 fmt.Println("Log in successful")
 
+		err = filter.resolve(ctx, c, u.User)
+		if err != nil {
+			fmt.Println("Unable to apply tag filter: ", err)
+			return
+		}
+
 		m := view.NewManager(c)
 
 //--- Get Host Info. Create a view of HostSystem objects from the RootFolder
@@ -104,6 +274,9 @@ func main() {
 		fmt.Fprintf(tw, "\n- List of ALL hosts:\n")
 
 		for _, hs := range hss {
+			if !filter.keep(hs.Reference()) {
+				continue
+			}
 			fmt.Fprintf(tw, "- %s:\t%s\n", hs.Summary.Config.Name, hs.Reference())
 		}
 
@@ -130,10 +303,17 @@ func main() {
 
 		tw = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
 		for _, ds := range dss {
+			if !filter.keep(ds.Reference()) {
+				continue
+			}
 			fmt.Fprintf(tw, "-- %s:\t%s\n", ds.Summary.Name, ds.Reference())
 		}
 
 //--- Get Network Info. Create a view of Network objects from the RootFolder
+//
+// The Network view also returns its sub-types, DistributedVirtualPortgroup and OpaqueNetwork (NSX segments),
+// so this one view covers every kind of network. The reference type tells them apart.
+//
 
 		v3, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"Network"}, true)
 		if err != nil {
@@ -144,20 +324,257 @@ func main() {
 
 		var nws []mo.Network
 //
-// Retrieve the network list -- there is no "summary" property for network which is why you can use either name or nil here
+// Retrieve the network list -- there is no "summary" property with names in it for network, but the host and vm
+// properties list everything attached to the network
 //
 
-		//err = v3.Retrieve(ctx, []string{"Network"}, nil, &nws)
-		err = v3.Retrieve(ctx, []string{"Network"}, []string{"name"}, &nws)
+		err = v3.Retrieve(ctx, []string{"Network"}, []string{"name", "summary", "host", "vm"}, &nws)
 		if err != nil {
        	        	fmt.Println("error 6")
 		}
 
+//--- Distributed port groups carry their switch and VLAN in their config
+
+		v4, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualPortgroup"}, true)
+		if err != nil {
+       	        	fmt.Println("error 7")
+		}
+
+		defer v4.Destroy(ctx)
+
+		var pgs []mo.DistributedVirtualPortgroup
+
+		err = v4.Retrieve(ctx, []string{"DistributedVirtualPortgroup"}, []string{"config"}, &pgs)
+		if err != nil {
+       	        	fmt.Println("error 8")
+		}
+
+		v5, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualSwitch"}, true)
+		if err != nil {
+       	        	fmt.Println("error 9")
+		}
+
+		defer v5.Destroy(ctx)
+
+		var dvss []mo.DistributedVirtualSwitch
+
+		err = v5.Retrieve(ctx, []string{"DistributedVirtualSwitch"}, []string{"name"}, &dvss)
+		if err != nil {
+       	        	fmt.Println("error 10")
+		}
+
+//--- Standard port groups, standard vSwitches and NSX opaque switches are defined on each host
+
+		var hns []mo.HostSystem
+
+		err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"name", "config.network"}, &hns)
+		if err != nil {
+       	        	fmt.Println("error 11")
+		}
+
+//
+// Build the lookups: MoRef -> name for hosts, VMs and switches, and port group key -> DVPG config
+//
+
+		names := make(map[types.ManagedObjectReference]string)
+
+		for _, hs := range hss {
+			names[hs.Reference()] = hs.Summary.Config.Name
+		}
+
+		for _, sw := range dvss {
+			names[sw.Reference()] = sw.Name
+		}
+
+		pgConfig := make(map[types.ManagedObjectReference]types.DVPortgroupConfigInfo)
+
+		for _, pg := range pgs {
+			pgConfig[pg.Reference()] = pg.Config
+		}
+
+		v6, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+		if err != nil {
+       	        	fmt.Println("error 12")
+		}
+
+		defer v6.Destroy(ctx)
+
+		var vms []mo.VirtualMachine
+
+		err = v6.Retrieve(ctx, []string{"VirtualMachine"}, []string{"name"}, &vms)
+		if err != nil {
+       	        	fmt.Println("error 13")
+		}
+
+		for _, vm := range vms {
+			names[vm.Reference()] = vm.Name
+		}
+
+		var rows []networkRow
+
+		for _, nw := range nws {
+			row := networkRow{
+				ref:   nw.Reference(),
+				name:  nw.Name,
+				hosts: refNames(nw.Host, names),
+				vms:   refNames(nw.Vm, names),
+			}
+
+			switch nw.Reference().Type {
+			case "DistributedVirtualPortgroup":
+				config := pgConfig[nw.Reference()]
+				row.kind = "distributed portgroup"
+				if config.BackingType == "nsx" {
+					row.kind = "NSX segment (VDS)"
+				}
+				if config.DistributedVirtualSwitch != nil {
+					row.backing = names[*config.DistributedVirtualSwitch]
+				}
+				row.vlan = vlanString(config.DefaultPortConfig)
+
+			case "OpaqueNetwork":
+
+//
+// An opaque network is attached to an NSX opaque switch (N-VDS) on each host through a physical NIC zone
+//
+
+				row.kind = "opaque network"
+				row.vlan = "-"
+				backing := make(map[string]bool)
+
+				if summary, ok := nw.Summary.(*types.OpaqueNetworkSummary); ok {
+					row.kind = "opaque network (" + summary.OpaqueNetworkType + ")"
+
+					for _, hs := range hns {
+						if hs.Config == nil || hs.Config.Network == nil {
+							continue
+						}
+						for _, on := range hs.Config.Network.OpaqueNetwork {
+							if on.OpaqueNetworkId != summary.OpaqueNetworkId {
+								continue
+							}
+							for _, sw := range hs.Config.Network.OpaqueSwitch {
+								for _, zone := range sw.PnicZone {
+									for _, key := range on.PnicZone {
+										if zone.Key == key {
+											backing[sw.Name] = true
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+				row.backing = joinSet(backing)
+
+			default:
+				row.kind = "standard portgroup"
+				vswitches := make(map[string]bool)
+				vlans := make(map[string]bool)
+
+				for _, hs := range hns {
+					if hs.Config == nil || hs.Config.Network == nil {
+						continue
+					}
+					for _, pg := range hs.Config.Network.Portgroup {
+						if pg.Spec.Name == nw.Name {
+							vswitches[pg.Spec.VswitchName] = true
+							vlans[fmt.Sprintf("%d", pg.Spec.VlanId)] = true
+						}
+					}
+				}
+				row.backing = joinSet(vswitches)
+				row.vlan = joinSet(vlans)
+			}
+
+			if filter.keep(row.ref) {
+				rows = append(rows, row)
+			}
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			if rows[i].kind != rows[j].kind {
+				return rows[i].kind < rows[j].kind
+			}
+			return rows[i].name < rows[j].name
+		})
+
+		_ = tw.Flush()
+
+		tw = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
 		fmt.Fprintf(tw, "\n--- List of ALL Networks:\n")
+		fmt.Fprintf(tw, "Name\tType\tSwitch\tVLAN\tHosts\tVMs\tMoRef\n")
+
+		for _, row := range rows {
+			fmt.Fprintf(tw, "--- %s\t%s\t%s\t%s\t%d\t%d\t%s\n", row.name, row.kind, row.backing, row.vlan, len(row.hosts), len(row.vms), row.ref)
+		}
+		_ = tw.Flush()
+
+//--- Which hosts and VMs are attached to each network
+
+		fmt.Printf("\n--- Network attachments:\n")
+
+		for _, row := range rows {
+			fmt.Printf("--- %s (%s)\n", row.name, row.kind)
+			fmt.Printf("      hosts: %s\n", strings.Join(row.hosts, ", "))
+			fmt.Printf("      vms:   %s\n", strings.Join(row.vms, ", "))
+		}
+
+//--- Standard vSwitches and NSX opaque switches (N-VDS) per host
+
+		sort.Slice(hns, func(i, j int) bool { return hns[i].Name < hns[j].Name })
 
 		tw = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-		for _, nw := range nws {
-			fmt.Fprintf(tw, "--- %s:\t%s\n", nw.Name, nw.Reference())
+		fmt.Fprintf(tw, "\n---- List of ALL standard vSwitches:\n")
+		fmt.Fprintf(tw, "Host\tvSwitch\tMTU\tPorts\tUplinks\tPort Groups (VLAN)\n")
+
+		for _, hs := range hns {
+			if hs.Config == nil || hs.Config.Network == nil || !filter.keep(hs.Reference()) {
+				continue
+			}
+
+			pnics := make(map[string]string)
+			for _, pnic := range hs.Config.Network.Pnic {
+				pnics[pnic.Key] = pnic.Device
+			}
+
+			for _, vs := range hs.Config.Network.Vswitch {
+				var uplinks, portgroups []string
+				for _, key := range vs.Pnic {
+					uplinks = append(uplinks, pnics[key])
+				}
+				for _, pg := range hs.Config.Network.Portgroup {
+					if pg.Spec.VswitchName == vs.Name {
+						portgroups = append(portgroups, fmt.Sprintf("%s (%d)", pg.Spec.Name, pg.Spec.VlanId))
+					}
+				}
+				fmt.Fprintf(tw, "---- %s\t%s\t%d\t%d\t%s\t%s\n", hs.Name, vs.Name, vs.Mtu, vs.NumPorts, strings.Join(uplinks, ","), strings.Join(portgroups, ", "))
+			}
+		}
+		_ = tw.Flush()
+
+		tw = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "\n----- List of ALL opaque switches:\n")
+		fmt.Fprintf(tw, "Host\tSwitch\tStatus\tUplinks\tOpaque Networks\n")
+
+		for _, hs := range hns {
+			if hs.Config == nil || hs.Config.Network == nil || !filter.keep(hs.Reference()) {
+				continue
+			}
+
+			for _, sw := range hs.Config.Network.OpaqueSwitch {
+				var networks []string
+				for _, on := range hs.Config.Network.OpaqueNetwork {
+					for _, zone := range sw.PnicZone {
+						for _, key := range on.PnicZone {
+							if zone.Key == key {
+								networks = append(networks, on.OpaqueNetworkName)
+							}
+						}
+					}
+				}
+				fmt.Fprintf(tw, "----- %s\t%s\t%s\t%s\t%s\n", hs.Name, sw.Name, sw.Status, strings.Join(sw.Pnic, ","), strings.Join(networks, ", "))
+			}
 		}
 		_ = tw.Flush()
 	}
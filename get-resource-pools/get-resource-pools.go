@@ -0,0 +1,265 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and walk the resource pool tree of each cluster, showing the CPU and memory shares,
+//			reservation, limit and expandable reservation of every pool, its runtime usage and the VMs
+//			in it
+//
+//			vSphere with Tanzu keeps a pool per Supervisor namespace below the cluster's "Namespaces"
+//			pool - these are marked as namespaces, with the vSphere Pods and TKG cluster VMs they hold.
+//			-cluster limits the report to clusters whose name matches a pattern, -no-vms leaves out the VMs.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// namespacesPool is the pool vSphere with Tanzu creates below the root pool of a Supervisor cluster
+const namespacesPool = "Namespaces"
+
+// shares prints a shares setting as its level, with the number of shares for a custom level
+func shares(s *types.SharesInfo) string {
+	if s == nil {
+		return "-"
+	}
+	if s.Level == types.SharesLevelCustom {
+		return fmt.Sprintf("custom (%d)", s.Shares)
+	}
+	return fmt.Sprintf("%s (%d)", s.Level, s.Shares)
+}
+
+// amount prints a reservation or limit, -1 meaning no limit
+func amount(v *int64, unit string) string {
+	if v == nil {
+		return "-"
+	}
+	if *v < 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d %s", *v, unit)
+}
+
+func yesNo(b *bool) string {
+	if b != nil && *b {
+		return "yes"
+	}
+	return "no"
+}
+
+//
+// Report walks the pools from each cluster's root pool and writes one row per pool, followed by its VMs
+//
+
+type Report struct {
+	tw     *tabwriter.Writer
+	pools  map[types.ManagedObjectReference]mo.ResourcePool
+	vms    map[types.ManagedObjectReference]mo.VirtualMachine
+	showVM bool
+}
+
+func (r *Report) walk(ref types.ManagedObjectReference, depth int, kind string) {
+	pool, ok := r.pools[ref]
+	if !ok {
+		return
+	}
+
+	indent := strings.Repeat("  ", depth)
+
+	if ref.Type == "VirtualApp" {
+		kind = "vApp"
+	}
+
+	cpu := pool.Config.CpuAllocation
+	mem := pool.Config.MemoryAllocation
+
+	// Runtime usage - CPU is in MHz, memory in bytes
+
+	fmt.Fprintf(r.tw, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d MHz\t%d MB\t%d\n",
+		indent, pool.Name, kind,
+		shares(cpu.Shares), amount(cpu.Reservation, "MHz"), amount(cpu.Limit, "MHz"), yesNo(cpu.ExpandableReservation),
+		shares(mem.Shares), amount(mem.Reservation, "MB"), amount(mem.Limit, "MB"), yesNo(mem.ExpandableReservation),
+		pool.Runtime.Cpu.OverallUsage, pool.Runtime.Memory.OverallUsage>>20, len(pool.Vm))
+
+	if r.showVM {
+		var vms []mo.VirtualMachine
+		for _, vm := range pool.Vm {
+			if v, ok := r.vms[vm]; ok {
+				vms = append(vms, v)
+			}
+		}
+		sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+
+		for _, vm := range vms {
+			fmt.Fprintf(r.tw, "%s  %s\tVM (%s)\t\t\t\t\t\t\t\t\t\t\t\n", indent, vm.Name, vm.Runtime.PowerState)
+		}
+	}
+
+	children := append([]types.ManagedObjectReference{}, pool.ResourcePool...)
+	sort.Slice(children, func(i, j int) bool { return r.pools[children[i]].Name < r.pools[children[j]].Name })
+
+	for _, child := range children {
+		childKind := "pool"
+		switch {
+		case kind == "root" && r.pools[child].Name == namespacesPool:
+			childKind = "namespaces"
+		case kind == "namespaces":
+			childKind = "namespace"
+		}
+		r.walk(child, depth+1, childKind)
+	}
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var pattern string
+	var noVMs bool
+
+	flag.StringVar(&pattern, "cluster", "*", "only clusters whose name matches this pattern")
+	flag.BoolVar(&noVMs, "no-vms", false, "leave the VMs of each pool out of the report")
+	flag.Parse()
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := view.NewManager(c)
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"ClusterComputeResource", "ResourcePool", "VirtualMachine"}, true)
+	if err != nil {
+		fmt.Printf("Could not create container view, error %v\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	var clusters []mo.ClusterComputeResource
+	err = v.RetrieveWithFilter(ctx, []string{"ClusterComputeResource"}, []string{"name", "resourcePool"}, &clusters, property.Filter{"name": pattern})
+	if err != nil {
+		fmt.Printf("Could not get list of clusters, error %v\n", err)
+		return
+	}
+
+	if len(clusters) == 0 {
+		fmt.Printf("No clusters match %s\n", pattern)
+		return
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	//
+	// Every pool (vApps included, they are pools too) and VM in two calls, rather than one call per pool
+	//
+
+	var pools []mo.ResourcePool
+	err = v.Retrieve(ctx, []string{"ResourcePool"}, []string{"name", "config", "runtime", "resourcePool", "vm"}, &pools)
+	if err != nil {
+		fmt.Printf("Could not get resource pools, error %v\n", err)
+		return
+	}
+
+	report := Report{
+		tw:     tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0),
+		pools:  make(map[types.ManagedObjectReference]mo.ResourcePool),
+		vms:    make(map[types.ManagedObjectReference]mo.VirtualMachine),
+		showVM: !noVMs,
+	}
+
+	for _, pool := range pools {
+		report.pools[pool.Self] = pool
+	}
+
+	if report.showVM {
+		var vms []mo.VirtualMachine
+		err = v.Retrieve(ctx, []string{"VirtualMachine"}, []string{"name", "runtime.powerState"}, &vms)
+		if err != nil {
+			fmt.Printf("Could not get VMs, error %v\n", err)
+			return
+		}
+		for _, vm := range vms {
+			report.vms[vm.Self] = vm
+		}
+	}
+
+	for _, cluster := range clusters {
+		fmt.Printf("\n*** Cluster %s ***\n\n", cluster.Name)
+
+		if cluster.ResourcePool == nil {
+			fmt.Printf("no resource pools\n")
+			continue
+		}
+
+		fmt.Fprintf(report.tw, "Pool\tType\tCPU Shares\tCPU Reservation\tCPU Limit\tCPU Expandable\tMem Shares\tMem Reservation\tMem Limit\tMem Expandable\tCPU Used\tMem Used\tVMs\n")
+		report.walk(*cluster.ResourcePool, 0, "root")
+		_ = report.tw.Flush()
+	}
+}
@@ -0,0 +1,409 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and report on every VM snapshot - the snapshot tree, description, creation
+//			time, age, quiesced state and size on disk - with the total snapshot space per datastore
+//
+//			  -warn 3d -crit 7d        age thresholds for the Status column
+//			  -older 14d               only list snapshots older than this
+//			  -vm 'web-*'              only look at VMs whose name matches
+//			  -remove                  remove the listed (-older) snapshots
+//			  -consolidate             consolidate the disks of VMs that vSphere flags as needing it
+//			  -apply                   without -apply, -remove and -consolidate only print what they would do
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// parseAge accepts anything time.ParseDuration does, plus whole days such as "7d"
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatAge prints an age as days and hours, which is what matters for snapshots
+func formatAge(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh", days, hours)
+	}
+	return fmt.Sprintf("%dh%dm", hours, int(d.Minutes())%60)
+}
+
+// datastoreName returns the datastore part of a "[datastore] folder/file" path
+func datastoreName(file string) string {
+	if strings.HasPrefix(file, "[") {
+		if i := strings.Index(file, "]"); i > 0 {
+			return file[1:i]
+		}
+	}
+	return "(unknown)"
+}
+
+//
+// Snapshot is one row of the report. Depth is the position in the VM's snapshot tree.
+//
+
+type Snapshot struct {
+	VM          *mo.VirtualMachine
+	Ref         types.ManagedObjectReference
+	Name        string
+	Description string
+	Created     time.Time
+	Quiesced    bool
+	Current     bool
+	Size        int64
+	Depth       int
+}
+
+func walkSnapshots(vm *mo.VirtualMachine, trees []types.VirtualMachineSnapshotTree, parent *types.ManagedObjectReference, depth int, out *[]Snapshot) {
+	for _, t := range trees {
+		current := vm.Snapshot.CurrentSnapshot != nil && *vm.Snapshot.CurrentSnapshot == t.Snapshot
+
+		var size int64
+		if vm.LayoutEx != nil {
+			size = int64(object.SnapshotSize(t.Snapshot, parent, vm.LayoutEx, current))
+		}
+
+		*out = append(*out, Snapshot{
+			VM:          vm,
+			Ref:         t.Snapshot,
+			Name:        t.Name,
+			Description: t.Description,
+			Created:     t.CreateTime,
+			Quiesced:    t.Quiesced,
+			Current:     current,
+			Size:        size,
+			Depth:       depth,
+		})
+
+		ref := t.Snapshot
+		walkSnapshots(vm, t.ChildSnapshotList, &ref, depth+1, out)
+	}
+}
+
+//
+// snapshotSpace adds up the files a VM's snapshots occupy on each datastore - the .vmsn/.vmem files
+// and every delta disk, i.e. each disk chain apart from its base disk
+//
+
+func snapshotSpace(vm *mo.VirtualMachine, space map[string]int64) {
+	if vm.LayoutEx == nil {
+		return
+	}
+
+	keys := make(map[int32]bool)
+	for _, s := range vm.LayoutEx.Snapshot {
+		keys[s.DataKey] = true
+		keys[s.MemoryKey] = true
+	}
+	for _, disk := range vm.LayoutEx.Disk {
+		for i, chain := range disk.Chain {
+			if i == 0 {
+				continue
+			}
+			for _, key := range chain.FileKey {
+				keys[key] = true
+			}
+		}
+	}
+
+	for _, file := range vm.LayoutEx.File {
+		if keys[file.Key] {
+			space[datastoreName(file.Name)] += file.Size
+		}
+	}
+}
+
+func wait(ctx context.Context, c *vim25.Client, task types.ManagedObjectReference) error {
+	return object.NewTask(c, task).Wait(ctx)
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var warnFlag, critFlag, olderFlag, vmName string
+	var remove, consolidate, apply bool
+	flag.StringVar(&warnFlag, "warn", "3d", "snapshots older than this are flagged WARN")
+	flag.StringVar(&critFlag, "crit", "7d", "snapshots older than this are flagged CRIT")
+	flag.StringVar(&olderFlag, "older", "", "only list snapshots older than this, e.g. 14d or 36h")
+	flag.StringVar(&vmName, "vm", "*", "only look at VMs whose name matches this pattern")
+	flag.BoolVar(&remove, "remove", false, "remove the listed snapshots (needs -older)")
+	flag.BoolVar(&consolidate, "consolidate", false, "consolidate the disks of VMs that need it")
+	flag.BoolVar(&apply, "apply", false, "carry out -remove/-consolidate rather than only printing them")
+	flag.Parse()
+
+	warn, err := parseAge(warnFlag)
+	if err != nil {
+		fmt.Printf("-warn: %v\n", err)
+		return
+	}
+	crit, err := parseAge(critFlag)
+	if err != nil {
+		fmt.Printf("-crit: %v\n", err)
+		return
+	}
+
+	var older time.Duration
+	if olderFlag != "" {
+		if older, err = parseAge(olderFlag); err != nil {
+			fmt.Printf("-older: %v\n", err)
+			return
+		}
+	}
+
+	// Removing every snapshot in the inventory is never what anybody wants, so -remove needs an age
+
+	if remove && olderFlag == "" {
+		fmt.Printf("-remove needs -older to say which snapshots to remove\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := view.NewManager(c)
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	if err != nil {
+		fmt.Printf("Could not create container view, error %v\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	//
+	// Only the VMs with snapshots or pending consolidation are of interest, but neither can be used as a
+	// property filter, so fetch the VMs and their snapshot layout and drop the rest here
+	//
+
+	refs, err := v.Find(ctx, []string{"VirtualMachine"}, property.Filter{"name": vmName})
+	if err != nil {
+		fmt.Printf("Could not find VMs, error %v\n", err)
+		return
+	}
+
+	var vms []mo.VirtualMachine
+	if len(refs) > 0 {
+		err = property.DefaultCollector(c).Retrieve(ctx, refs, []string{"name", "snapshot", "layoutEx", "runtime.consolidationNeeded"}, &vms)
+		if err != nil {
+			fmt.Printf("Could not retrieve VM snapshots, error %v\n", err)
+			return
+		}
+	}
+
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+
+	now := time.Now()
+	space := make(map[string]int64)
+
+	var snapshots []Snapshot
+	var needConsolidation []*mo.VirtualMachine
+
+	for i := range vms {
+		vm := &vms[i]
+		if vm.Runtime.ConsolidationNeeded != nil && *vm.Runtime.ConsolidationNeeded {
+			needConsolidation = append(needConsolidation, vm)
+		}
+		if vm.Snapshot == nil {
+			continue
+		}
+		walkSnapshots(vm, vm.Snapshot.RootSnapshotList, nil, 0, &snapshots)
+		snapshotSpace(vm, space)
+	}
+
+	var listed []Snapshot
+	for _, s := range snapshots {
+		if now.Sub(s.Created) >= older {
+			listed = append(listed, s)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+
+	fmt.Printf("\n*** Snapshot Information ***\n")
+	fmt.Printf("----------------------------\n\n")
+	fmt.Fprintf(tw, "VM:\tSnapshot:\tDescription:\tCreated:\tAge:\tQuiesced:\tSize:\tStatus:\n")
+
+	var total int64
+	for _, s := range listed {
+		age := now.Sub(s.Created)
+
+		status := "OK"
+		if age >= crit {
+			status = "CRIT"
+		} else if age >= warn {
+			status = "WARN"
+		}
+
+		name := strings.Repeat("  ", s.Depth) + s.Name
+		if s.Current {
+			name += " (current)"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\t%s\t%s\n", s.VM.Name, name, s.Description, s.Created.Local().Format("2006-01-02 15:04"),
+			formatAge(age), s.Quiesced, units.ByteSize(s.Size), status)
+		total += s.Size
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\n%d snapshot(s), %s\n", len(listed), units.ByteSize(total))
+
+	var datastores []string
+	for ds := range space {
+		datastores = append(datastores, ds)
+	}
+	sort.Strings(datastores)
+
+	fmt.Printf("\n*** Snapshot Space per Datastore ***\n")
+	fmt.Printf("-------------------------------------\n\n")
+	fmt.Fprintf(tw, "Datastore:\tSnapshot Space:\n")
+	for _, ds := range datastores {
+		fmt.Fprintf(tw, "%s\t%s\n", ds, units.ByteSize(space[ds]))
+	}
+	_ = tw.Flush()
+
+	if len(needConsolidation) > 0 {
+		fmt.Printf("\nVMs needing disk consolidation:\n")
+		for _, vm := range needConsolidation {
+			fmt.Printf("  %s\n", vm.Name)
+		}
+	}
+
+	if !remove && !consolidate {
+		return
+	}
+
+	//
+	// Actions. Each snapshot is removed on its own (children are kept and the disks consolidated), oldest
+	// first, and one failure does not stop the rest
+	//
+
+	fmt.Println()
+	if !apply {
+		fmt.Printf("Dry run - re-run with -apply to make these changes\n\n")
+	}
+
+	yes := true
+
+	if remove {
+		sort.SliceStable(listed, func(i, j int) bool { return listed[i].Created.Before(listed[j].Created) })
+
+		for _, s := range listed {
+			fmt.Printf("- %s snapshot %q (%s)\n", s.VM.Name, s.Name, formatAge(now.Sub(s.Created)))
+			if !apply {
+				continue
+			}
+
+			req := types.RemoveSnapshot_Task{This: s.Ref, RemoveChildren: false, Consolidate: &yes}
+			res, err := methods.RemoveSnapshot_Task(ctx, c, &req)
+			if err == nil {
+				err = wait(ctx, c, res.Returnval)
+			}
+			if err != nil {
+				fmt.Printf("! could not remove %q from %s, error %v\n", s.Name, s.VM.Name, err)
+			}
+		}
+	}
+
+	if consolidate {
+		for _, vm := range needConsolidation {
+			fmt.Printf("~ %s consolidate disks\n", vm.Name)
+			if !apply {
+				continue
+			}
+
+			req := types.ConsolidateVMDisks_Task{This: vm.Self}
+			res, err := methods.ConsolidateVMDisks_Task(ctx, c, &req)
+			if err == nil {
+				err = wait(ctx, c, res.Returnval)
+			}
+			if err != nil {
+				fmt.Printf("! could not consolidate %s, error %v\n", vm.Name, err)
+			}
+		}
+	}
+}
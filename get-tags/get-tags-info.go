@@ -1,196 +1,847 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and report on tags, their categories and the objects they are attached to
+//
+//			Tags and categories come from the vAPI (REST) tags.Manager. The tagged objects are returned
+//			as MoRefs only, so the property collector is used to turn each one back into a name and
+//			an inventory path.
+//
+//			  -group category     category -> tag -> objects (default)
+//			  -group object       object -> category:tag list
+//			  -category name      only report the tags in this category
+//			  -audit policy.yaml  report every object that breaks the tag policy (-remediate attaches defaults)
+//
+//			A policy requires a tag from some categories on every object of a type:
+//
+//			  rules:
+//			  - type: VirtualMachine
+//			    path: /DC0/vm/prod            # optional, only objects under this inventory path
+//			    require:
+//			    - category: owner
+//			    - category: env
+//			      allowed: [prod, dev]        # optional, other env tags are violations
+//			      default: dev                # optional, attached by -remediate when env is missing
+//			  - type: HostSystem
+//			    path: /DC0/host/*-edge        # wildcards match one path element, here any *-edge cluster
+//			    require:
+//			    - category: rack
+//
+//			A path covers the objects below it at any depth - /DC0/vm/prod covers /DC0/vm/prod/web-01
+//			as well as /DC0/vm/prod/app/tier1/db-01, and /DC0/host/*-edge every host in those clusters.
+//
+//			The tags.Manager Get* helpers make one REST call per tag or category, one after the other,
+//			which takes minutes with thousands of tags. Instead the IDs are listed per category and
+//			the tags and categories are fetched -workers at a time into an in-memory cache, and the
+//			attachments are listed for many tags per call.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"os"
+	"io/ioutil"
 	"net/url"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
 
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
 	"github.com/vmware/govmomi/vapi/rest"
 	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25"
-	"github.com/vmware/govmomi/vim25/soap"
-	"github.com/vmware/govmomi/session/cache"
 	"github.com/vmware/govmomi/vim25/mo"
-	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"sigs.k8s.io/yaml"
 )
 
+// attachmentBatch is the number of tag IDs sent in one list-attached-objects-on-tags call
+const attachmentBatch = 500
 
+// InventoryObject is a tagged object resolved from its MoRef
+type InventoryObject struct {
+	Ref  types.ManagedObjectReference
+	Name string
+	Path string
+}
 
-func main() {
-
-// We need to get 3 environment variables:
 //
-// GOVMOMI_URL
-// GOVMOMI_USERNAME
-// GOVMOMI_PASSWORD
+// vlogin returns both clients needed here - the vim25 client for the property collector,
+// and the rest client (which shares the vim25 session) for tags
+//
 
-        vc := os.Getenv ("GOVMOMI_URL")
-        user := os.Getenv ("GOVMOMI_USERNAME")
-        pwd := os.Getenv ("GOVMOMI_PASSWORD")
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, *rest.Client, error) {
 
+	u, err := soap.ParseURL(vc)
 
-//      fmt.Printf ("DEBUG: vc is %s\n", vc)
-//      fmt.Printf ("DEBUG: user is %s\n", user)
-//      fmt.Printf ("DEBUG: password is %s\n", pwd)
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
 
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, nil, err
+	}
 
-//
-// Imagine that there were multiple operations taking place such as processing some data, logging into vCenter, etc.
-// If one of the operations failed, the context would be used to share the fact that all of the other operations sharing that context needs cancelling.
-//
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in (vim25) not successful- could not get vCenter client: %v\n", err)
+		return nil, nil, err
+	}
+
+	rc := rest.NewClient(c)
+
+	err = s.Login(ctx, rc, nil)
+	if err != nil {
+		fmt.Printf("Log in (rest) not successful- could not get vCenter client: %v\n", err)
+		return nil, nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
 
-        ctx, cancel := context.WithCancel(context.Background())
-        defer cancel()
+	return c, rc, nil
+}
 
 //
-// Create a vSphere/vCenter client
+// forEach calls fn(0) to fn(n-1) with at most workers calls in flight, and returns the first error
 //
-//    The vim25 client requires a URL object, u, not just a string representation of the vCenter URL.
 
-        u, err := soap.ParseURL(vc)
+func forEach(n, workers int, fn func(i int) error) error {
+	sem := make(chan struct{}, workers)
+	errs := make(chan error, n)
+
+	var wg sync.WaitGroup
 
-        if u == nil {
-                fmt.Println("could not parse URL (environment variables set?)")
-        }
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
 
-        if err != nil {
-                fmt.Println("URL parsing not successful, error %v", err)
-        }
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(i); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
 
-        u.User = url.UserPassword(user, pwd)
 //
-// Share session cache
+// tagCache holds the tag and category metadata fetched so far, so each ID is only ever fetched once
 //
-        s := &cache.Session{
-                URL:      u,
-               Insecure: true,
-        }
+
+type tagCache struct {
+	m       *tags.Manager
+	workers int
+
+	mu         sync.Mutex
+	categories map[string]tags.Category
+	tags       map[string]tags.Tag
+}
+
+func newTagCache(m *tags.Manager, workers int) *tagCache {
+	return &tagCache{
+		m:          m,
+		workers:    workers,
+		categories: make(map[string]tags.Category),
+		tags:       make(map[string]tags.Tag),
+	}
+}
+
+func (tc *tagCache) category(ctx context.Context, id string) (tags.Category, error) {
+	tc.mu.Lock()
+	cat, ok := tc.categories[id]
+	tc.mu.Unlock()
+	if ok {
+		return cat, nil
+	}
+
+	c, err := tc.m.GetCategory(ctx, id)
+	if err != nil {
+		return cat, fmt.Errorf("get category %s: %s", id, err)
+	}
+
+	tc.mu.Lock()
+	tc.categories[id] = *c
+	tc.mu.Unlock()
+
+	return *c, nil
+}
+
+func (tc *tagCache) tag(ctx context.Context, id string) (tags.Tag, error) {
+	tc.mu.Lock()
+	tag, ok := tc.tags[id]
+	tc.mu.Unlock()
+	if ok {
+		return tag, nil
+	}
+
+	t, err := tc.m.GetTag(ctx, id)
+	if err != nil {
+		return tag, fmt.Errorf("get tag %s: %s", id, err)
+	}
+
+	tc.mu.Lock()
+	tc.tags[id] = *t
+	tc.mu.Unlock()
+
+	return *t, nil
+}
 
 //
-//    c - Return the client object c
-//    err - Return the error object err
-//    ctx - Pass in the shared context
+// loadCategories fetches every category, or only the named one
 //
-       	c := new (vim25.Client)
 
-	err = s.Login(ctx, c, nil)
-        if err != nil {
-                fmt.Println("Log in (vim25) not successful- could not get vCenter client: %v", err)
-        } else {
-                fmt.Println("Log in (vim25) successful")
+func (tc *tagCache) loadCategories(ctx context.Context, name string) ([]tags.Category, error) {
+	if name != "" {
+		cat, err := tc.m.GetCategory(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tc.categories[cat.ID] = *cat
+		return []tags.Category{*cat}, nil
+	}
+
+	ids, err := tc.m.ListCategories(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	err = forEach(len(ids), tc.workers, func(i int) error {
+		_, err := tc.category(ctx, ids[i])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []tags.Category
+	for _, id := range ids {
+		categories = append(categories, tc.categories[id])
+	}
+
+	return categories, nil
+}
+
 //
-// -- Just get tags of VMs -- e.g. https://github.com/vmware/govmomi/blob/master/vapi/tags/example_test.go
+// loadTags lists the tag IDs of each category, then fetches the tags themselves
 //
 
-	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+func (tc *tagCache) loadTags(ctx context.Context, categories []tags.Category) ([]tags.Tag, error) {
+	var mu sync.Mutex
+	var ids []string
+
+	err := forEach(len(categories), tc.workers, func(i int) error {
+		tagIDs, err := tc.m.ListTagsForCategory(ctx, categories[i].ID)
+		if err != nil {
+			return fmt.Errorf("list tags for category %s: %s", categories[i].Name, err)
+		}
+		mu.Lock()
+		ids = append(ids, tagIDs...)
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
+		return nil, err
 	}
 
-// List all VMs in the inventory
-	vms, err := v.Find(ctx, nil, property.Filter{})
+	err = forEach(len(ids), tc.workers, func(i int) error {
+		_, err := tc.tag(ctx, ids[i])
+		return err
+	})
 	if err != nil {
-                fmt.Println("Error in find", err)
+		return nil, err
 	}
 
-// Convert list type
-	refs := make([]mo.Reference, len(vms))
-	for i := range vms {
-		refs[i] = vms[i]
+	var tagList []tags.Tag
+	for _, id := range ids {
+		tagList = append(tagList, tc.tags[id])
 	}
 
+	return tagList, nil
+}
+
 //
-// -- This section of code will print tags associated with VMs only, which will have a list of refs for
+// attachedObjects lists the objects on each tag, attachmentBatch tags per call. This uses the List variant
+// because GetAttachedObjectsOnTags makes a GetTag call per returned entry, and the tags are already cached.
 //
-        fmt.Println("\n-- First mechanism -- VM Objects\n")
 
+func (tc *tagCache) attachedObjects(ctx context.Context, tagIDs []string) ([]tags.AttachedObjects, error) {
+	batches := (len(tagIDs) + attachmentBatch - 1) / attachmentBatch
+
+	var mu sync.Mutex
+	var attached []tags.AttachedObjects
+
+	err := forEach(batches, tc.workers, func(b int) error {
+		i := b * attachmentBatch
+		end := i + attachmentBatch
+		if end > len(tagIDs) {
+			end = len(tagIDs)
+		}
+
+		res, err := tc.m.ListAttachedObjectsOnTags(ctx, tagIDs[i:end])
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		attached = append(attached, res...)
+		mu.Unlock()
+		return nil
+	})
+
+	return attached, err
+}
 
 //
-// -- using the rest client for tags (rest client uses vim25 client)
-//
-//    rc - Return the client object rc
-//    err - Return the error object err
-//    ctx - Pass in the shared context
+// resolveObjects turns MoRefs into names and inventory paths. It retrieves "name" and "parent" for all
+// objects in one property collector call, then does the same for their parents, level by level, until
+// the root folder is reached - so the number of calls depends on the depth of the inventory, not the
+// number of objects.
 //
 
-	rc := rest.NewClient(c)
+func resolveObjects(ctx context.Context, c *vim25.Client, refs []types.ManagedObjectReference) map[types.ManagedObjectReference]InventoryObject {
+	pc := property.DefaultCollector(c)
 
-	err = s.Login(ctx, rc, nil)
-        if err != nil {
-                fmt.Println("Log in (rest) not successful- could not get vCenter client: %v", err)
-        } else {
-                fmt.Println("Log in (rest) successful")
+	// The raw ObjectContent is read rather than mo.ManagedEntity - the mo.Network types have their own Name
+	// field, so the name of a network or port group is lost when it is loaded as a ManagedEntity
+
+	type entity struct {
+		name   string
+		parent *types.ManagedObjectReference
 	}
-	m := tags.NewManager(rc)
-	attached, err := m.GetAttachedTagsOnObjects(ctx, refs)
 
-       	for _, id := range attached {
-		fmt.Println("\tFound Tags :", id.Tags)
+	entities := make(map[types.ManagedObjectReference]entity)
+	pending := refs
+
+	for len(pending) > 0 {
+		var objs []types.ObjectContent
+
+		err := pc.Retrieve(ctx, pending, []string{"name", "parent"}, &objs)
+		if err != nil {
+
+			// A tag can still point at an object that has been deleted, which fails the whole call,
+			// so fall back to one object at a time and skip the ones that are gone
+
+			objs = nil
+			for _, ref := range pending {
+				var obj []types.ObjectContent
+				if pc.Retrieve(ctx, []types.ManagedObjectReference{ref}, []string{"name", "parent"}, &obj) == nil {
+					objs = append(objs, obj...)
+				}
+			}
+		}
+
+		queued := make(map[types.ManagedObjectReference]bool)
+		var next []types.ManagedObjectReference
+
+		for _, obj := range objs {
+			var e entity
+			for _, prop := range obj.PropSet {
+				switch val := prop.Val.(type) {
+				case string:
+					e.name = val
+				case types.ManagedObjectReference:
+					e.parent = &val
+				}
+			}
+			entities[obj.Obj] = e
+		}
+
+		for _, obj := range objs {
+			parent := entities[obj.Obj].parent
+			if parent == nil {
+				continue
+			}
+			if _, ok := entities[*parent]; ok || queued[*parent] {
+				continue
+			}
+			queued[*parent] = true
+			next = append(next, *parent)
+		}
+
+		pending = next
 	}
 
-        fmt.Println("\n")
-//
-// -- https://pkg.go.dev/github.com/vmware/govmomi/vapi/tags#Tag
-// -- https://pkg.go.dev/github.com/vmware/govmomi/vapi/tags#AttachedTags.Tags
-//
+	resolved := make(map[types.ManagedObjectReference]InventoryObject)
 
-        for _, vm := range attached {
-                fmt.Println("Found Tags on VM:", vm.ObjectID.Reference().Value)
+	for _, ref := range refs {
+		obj := InventoryObject{Ref: ref, Name: ref.Value, Path: "(not found)"}
 
-//
-// Convert from a reference back to a VM - somehow ...
-//
+		if e, ok := entities[ref]; ok {
+			obj.Name = e.name
 
-//
-// Now print the tags for the VM
-//
-                for _, found_tag := range vm.Tags {
-                        fmt.Println ("Found Tag Name: ", found_tag.Name)
-                }
-                fmt.Println("\n")
-        }
+			// Walk up to the root folder, which has no parent and is not part of the path
 
-//
-// Try another way -- for all Objects, not just VMs
-//
-        fmt.Println("\n-- Alternate mechanism -- All Objects\n")
+			var names []string
+			for cur, ok := e, true; ok && cur.parent != nil; cur, ok = entities[*cur.parent] {
+				names = append([]string{cur.name}, names...)
+			}
+			obj.Path = "/" + strings.Join(names, "/")
+		}
 
-//
-// Get a list of all tags
-//
-        tagList, err := m.ListTags(ctx)
-        if err != nil {
-                fmt.Println("Could not get list of tags, error %v\n", err)
-        }
+		resolved[ref] = obj
+	}
+
+	return resolved
+}
+
+// Policy is the desired tagging state checked by -audit
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule requires tags from some categories on every object of a type, optionally under an inventory path
+type PolicyRule struct {
+	Type    string        `json:"type"`
+	Path    string        `json:"path,omitempty"`
+	Require []Requirement `json:"require"`
+}
+
+// Requirement is one category an object must carry a tag from. Default is the tag -remediate attaches when
+// the object has none, and Allowed (if set) limits which tags of the category are acceptable.
+type Requirement struct {
+	Category string   `json:"category"`
+	Allowed  []string `json:"allowed,omitempty"`
+	Default  string   `json:"default,omitempty"`
+}
+
+func readPolicy(path string) (Policy, error) {
+	var policy Policy
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &policy)
+	if err != nil {
+		return policy, err
+	}
+
+	for i, rule := range policy.Rules {
+		if rule.Type == "" {
+			return policy, fmt.Errorf("rule %d has no type", i+1)
+		}
+		if rule.Path != "" {
+			if _, err := pathpkg.Match(rule.Path, ""); err != nil {
+				return policy, fmt.Errorf("rule %d: bad path pattern %q: %s", i+1, rule.Path, err)
+			}
+		}
+	}
+
+	return policy, nil
+}
 
-        for _, tag := range tagList {
-                fmt.Println("Found a Tag in list of Tags:", tag)
 //
-// Get Tag details
+// underPath reports whether the object path, or one of its ancestors, matches the rule's path pattern
 //
-                taginfo, err := m.GetTag(ctx, tag)
-		if err != nil {
-			fmt.Println("Could not get tag info, error %v\n", err)
+
+func underPath(pattern, path string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	for p := path; p != "/" && p != "."; p = pathpkg.Dir(p) {
+		if ok, _ := pathpkg.Match(pattern, p); ok {
+			return true
 		}
+	}
+
+	return false
+}
 
-		fmt.Println("Found Tag Name:", taginfo.Name)
 //
-// Get Inventory Items that are using the Tags
+// audit checks every object of the types named in the policy against its rules and prints the violations.
+// With remediate, a missing tag that has a default is attached (one call per default tag), and the table is
+// printed once the attach calls have returned - an object whose attach failed is still a violation. It returns
+// the number of violations left.
 //
-		attached2, err := m.GetAttachedObjectsOnTags(ctx, []string{tag})
+
+func audit(ctx context.Context, c *vim25.Client, tc *tagCache, policy Policy, categories []tags.Category, tagList []tags.Tag,
+	objectsByTag map[string][]types.ManagedObjectReference, remediate bool) (int, error) {
+
+	categoryByName := make(map[string]tags.Category)
+	for _, cat := range categories {
+		categoryByName[cat.Name] = cat
+	}
+
+	tagByName := make(map[string]tags.Tag)
+	for _, tag := range tagList {
+		tagByName[tag.CategoryID+"/"+tag.Name] = tag
+	}
+
+	for _, rule := range policy.Rules {
+		for _, req := range rule.Require {
+			cat, ok := categoryByName[req.Category]
+			if !ok {
+				return 0, fmt.Errorf("policy category %s does not exist", req.Category)
+			}
+			if _, ok := tagByName[cat.ID+"/"+req.Default]; req.Default != "" && !ok {
+				return 0, fmt.Errorf("policy default tag %s:%s does not exist", req.Category, req.Default)
+			}
+		}
+	}
+
+	tagsByObject := make(map[types.ManagedObjectReference][]tags.Tag)
+	for _, tag := range tagList {
+		for _, ref := range objectsByTag[tag.ID] {
+			tagsByObject[ref] = append(tagsByObject[ref], tag)
+		}
+	}
+
+	//
+	// Every object of the policy types is audited, tagged or not
+	//
+
+	kinds := make(map[string]bool)
+	var kindList []string
+	for _, rule := range policy.Rules {
+		if !kinds[rule.Type] {
+			kinds[rule.Type] = true
+			kindList = append(kindList, rule.Type)
+		}
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, kindList, true)
+	if err != nil {
+		return 0, fmt.Errorf("could not create container view: %s", err)
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, kindList, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not list %s objects: %s", strings.Join(kindList, ", "), err)
+	}
+
+	objects := resolveObjects(ctx, c, refs)
+
+	sort.Slice(refs, func(i, j int) bool { return objects[refs[i]].Path < objects[refs[j]].Path })
+
+	// fix is the default tag that -remediate attaches for a row, if any
+
+	type row struct {
+		path, kind, problem, action, fix string
+	}
+
+	var rows []row
+	fixes := make(map[string][]mo.Reference)
+
+	for _, ref := range refs {
+		obj := objects[ref]
+
+		for _, rule := range policy.Rules {
+			if rule.Type != ref.Type {
+				continue
+			}
+			if rule.Path != "" && !underPath(rule.Path, obj.Path) {
+				continue
+			}
+
+			for _, req := range rule.Require {
+				cat := categoryByName[req.Category]
+
+				var have []string
+				for _, tag := range tagsByObject[ref] {
+					if tag.CategoryID == cat.ID {
+						have = append(have, tag.Name)
+					}
+				}
+
+				if len(have) == 0 {
+					r := row{path: obj.Path, kind: ref.Type, problem: "no " + req.Category + " tag", action: "-"}
+					if req.Default != "" {
+						r.action = "attach " + req.Category + ":" + req.Default
+						if remediate {
+							tag := tagByName[cat.ID+"/"+req.Default]
+							fixes[tag.ID] = append(fixes[tag.ID], ref)
+							r.fix = tag.ID
+						}
+					}
+					rows = append(rows, r)
+					continue
+				}
+
+				if len(req.Allowed) == 0 {
+					continue
+				}
+
+				for _, name := range have {
+					allowed := false
+					for _, a := range req.Allowed {
+						if a == name {
+							allowed = true
+						}
+					}
+					if !allowed {
+						problem := fmt.Sprintf("%s:%s not allowed (%s)", req.Category, name, strings.Join(req.Allowed, ", "))
+						rows = append(rows, row{path: obj.Path, kind: ref.Type, problem: problem, action: "-"})
+					}
+				}
+			}
+		}
+	}
+
+	failed := make(map[string]error)
+	for tagID, objs := range fixes {
+		if err = tc.m.AttachTagToMultipleObjects(ctx, tagID, objs); err != nil {
+			failed[tagID] = err
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 4, ' ', 0)
+	fmt.Printf("\n*** Tag Policy Violations ***\n")
+	fmt.Printf("-----------------------------\n\n")
+	fmt.Fprintf(tw, "Path\tType\tProblem\tAction\n")
+	fmt.Fprintf(tw, "----\t----\t-------\t------\n")
+
+	violations := 0
+
+	for _, r := range rows {
+		switch {
+		case r.fix == "":
+			violations++
+		case failed[r.fix] != nil:
+			violations++
+			r.action = fmt.Sprintf("%s failed: %s", r.action, failed[r.fix])
+		default:
+			r.action = "attached" + strings.TrimPrefix(r.action, "attach")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.path, r.kind, r.problem, r.action)
+	}
+
+	_ = tw.Flush()
+
+	return violations, nil
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var group, category, policyFile string
+	var workers int
+	var remediate bool
+	flag.StringVar(&group, "group", "category", "group the report by \"category\" or by \"object\"")
+	flag.StringVar(&category, "category", "", "only report the tags in this category (name or ID)")
+	flag.IntVar(&workers, "workers", 8, "maximum number of concurrent tag/category requests")
+	flag.StringVar(&policyFile, "audit", "", "check every object against the tag policy in this file")
+	flag.BoolVar(&remediate, "remediate", false, "with -audit, attach the default tag where a required tag is missing")
+	flag.Parse()
+
+	if group != "category" && group != "object" {
+		fmt.Printf("-group must be \"category\" or \"object\"\n")
+		return
+	}
+
+	if workers < 1 {
+		fmt.Printf("-workers must be at least 1\n")
+		return
+	}
+
+	var policy Policy
+	if policyFile != "" {
+		var err error
+		if policy, err = readPolicy(policyFile); err != nil {
+			fmt.Printf("Could not read policy %s, error %v\n", policyFile, err)
+			return
+		}
+		category = ""
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, rc, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	//
+	// -- https://pkg.go.dev/github.com/vmware/govmomi/vapi/tags#Manager
+	//
+
+	tc := newTagCache(tags.NewManager(rc), workers)
+
+	categories, err := tc.loadCategories(ctx, category)
+	if err != nil {
+		fmt.Printf("Could not get list of categories, error %v\n", err)
+		return
+	}
+
+	categoryByID := make(map[string]tags.Category)
+	for _, cat := range categories {
+		categoryByID[cat.ID] = cat
+	}
+
+	tagList, err := tc.loadTags(ctx, categories)
+	if err != nil {
+		fmt.Printf("Could not get list of tags, error %v\n", err)
+		return
+	}
+
+	tagByID := make(map[string]tags.Tag)
+	var tagIDs []string
+	for _, tag := range tagList {
+		tagByID[tag.ID] = tag
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	attached, err := tc.attachedObjects(ctx, tagIDs)
+	if err != nil {
+		fmt.Printf("Could not get list of objects with tags, error %v\n", err)
+		return
+	}
+
+	objectsByTag := make(map[string][]types.ManagedObjectReference)
+	seen := make(map[types.ManagedObjectReference]bool)
+	var refs []types.ManagedObjectReference
+
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			ref := obj.Reference()
+			objectsByTag[a.TagID] = append(objectsByTag[a.TagID], ref)
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	if policyFile != "" {
+		violations, err := audit(ctx, c, tc, policy, categories, tagList, objectsByTag, remediate)
 		if err != nil {
-			fmt.Println("Could not get list of objects with tags, error %v\n", err)
+			fmt.Printf("Audit failed, error %v\n", err)
+			os.Exit(2)
+		}
+
+		fmt.Printf("\n%d violation(s)\n", violations)
+		if violations > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	objects := resolveObjects(ctx, c, refs)
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 4, ' ', 0)
+
+	if group == "object" {
+
+		//
+		// Object view - one row per tagged object with all of its category:tag pairs
+		//
+
+		tagsByObject := make(map[types.ManagedObjectReference][]string)
+		for tagID, objRefs := range objectsByTag {
+			tag := tagByID[tagID]
+			for _, ref := range objRefs {
+				tagsByObject[ref] = append(tagsByObject[ref], categoryByID[tag.CategoryID].Name+":"+tag.Name)
+			}
+		}
+
+		sort.Slice(refs, func(i, j int) bool { return objects[refs[i]].Path < objects[refs[j]].Path })
+
+		fmt.Printf("\n*** Tagged Objects ***\n")
+		fmt.Printf("----------------------\n\n")
+		fmt.Fprintf(tw, "Name\tType\tPath\tTags\n")
+		fmt.Fprintf(tw, "----\t----\t----\t----\n")
+
+		for _, ref := range refs {
+			obj := objects[ref]
+			sort.Strings(tagsByObject[ref])
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", obj.Name, ref.Type, obj.Path, strings.Join(tagsByObject[ref], ", "))
+		}
+
+		_ = tw.Flush()
+		return
+	}
+
+	//
+	// Category view - category (cardinality and associable types), then each tag and its objects
+	//
+
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	sort.Slice(tagList, func(i, j int) bool { return tagList[i].Name < tagList[j].Name })
+
+	fmt.Printf("\n*** Tags by Category ***\n")
+	fmt.Printf("------------------------\n")
+
+	for _, cat := range categories {
+		associable := "All objects"
+		if len(cat.AssociableTypes) > 0 {
+			associable = strings.Join(cat.AssociableTypes, ", ")
+		}
+
+		fmt.Printf("\nCategory: %s\n", cat.Name)
+		fmt.Printf("  Cardinality      : %s\n", cat.Cardinality)
+		fmt.Printf("  Associable Types : %s\n", associable)
+		if cat.Description != "" {
+			fmt.Printf("  Description      : %s\n", cat.Description)
 		}
 
-		for _, item := range attached2 {
-			fmt.Println("Found Inventory Item(s) with Tag:", item)
-			fmt.Println("\n")
+		for _, tag := range tagList {
+			if tag.CategoryID != cat.ID {
+				continue
+			}
+
+			fmt.Printf("\n  Tag: %s", tag.Name)
+			if tag.Description != "" {
+				fmt.Printf(" (%s)", tag.Description)
+			}
+			fmt.Printf("\n")
+
+			tagged := objectsByTag[tag.ID]
+			if len(tagged) == 0 {
+				fmt.Printf("    (not attached to any object)\n")
+				continue
+			}
+
+			sort.Slice(tagged, func(i, j int) bool { return objects[tagged[i]].Path < objects[tagged[j]].Path })
+
+			for _, ref := range tagged {
+				obj := objects[ref]
+				fmt.Fprintf(tw, "    %s\t%s\t%s\n", ref.Type, obj.Name, obj.Path)
+			}
+			_ = tw.Flush()
 		}
 	}
-	fmt.Println("\n")
 
+	fmt.Printf("\n")
 }
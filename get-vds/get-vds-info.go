@@ -3,30 +3,1383 @@
 // Description:		Go code to connect to vSphere via environment
 // 			variables and retrieve the VDS and VDS PortGroup Information
 //
+//			Every VLAN spec type (VLAN ID, trunk ranges, private VLAN) is decoded, along with
+//			the uplink teaming policy, security policy, MTU, NIOC, LACP groups and port counts.
+//
+//			Output is a table by default, or JSON with -json
+//
+//			The switch configuration can also be backed up and replicated to another vCenter:
+//
+//			  -export dvs.yaml               write switch settings, port groups, VLANs and policies
+//			  -import dvs.yaml               show a diff of what would be created or changed
+//			  -import dvs.yaml -apply        create the missing switches/port groups and reconcile the rest
+//
+//			Host membership, NIOC pool keys and LACP groups are tied to a vCenter and are not exported.
+//			Settings left out of an imported document are left alone, except that a list in the document
+//			(uplinks, trunk ranges, pvlanMap) replaces the live one. Without a pvlanMap the PVLANs are kept.
+//
+//			-tag category:name (repeatable, -tag-match all|any) scopes the report and -export to
+//			tagged switches and port groups.
+//
 // Author:		   	Cormac J. Hogan (VMware)
 //
-// Date:			04 Jul 2021
+// Date:			04 Jul 2021
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/yaml"
+)
+
+// VlanInfo is a flattened view of the three VLAN spec types a DVS port can carry
+type VlanInfo struct {
+	Type         string   `json:"type"`
+	VlanID       *int32   `json:"vlanId,omitempty"`
+	TrunkRanges  []string `json:"trunkRanges,omitempty"`
+	PvlanID      int32    `json:"pvlanId,omitempty"`
+	PvlanPrimary int32    `json:"pvlanPrimary,omitempty"`
+	PvlanType    string   `json:"pvlanType,omitempty"`
+}
+
+// TeamingInfo holds the uplink teaming and failover policy of a port setting
+type TeamingInfo struct {
+	Policy         string   `json:"policy,omitempty"`
+	NotifySwitches *bool    `json:"notifySwitches,omitempty"`
+	Failback       *bool    `json:"failback,omitempty"`
+	ActiveUplinks  []string `json:"activeUplinks,omitempty"`
+	StandbyUplinks []string `json:"standbyUplinks,omitempty"`
+}
+
+// SecurityInfo holds the layer 2 security policy of a port setting
+type SecurityInfo struct {
+	AllowPromiscuous *bool `json:"allowPromiscuous,omitempty"`
+	MacChanges       *bool `json:"macChanges,omitempty"`
+	ForgedTransmits  *bool `json:"forgedTransmits,omitempty"`
+}
+
+// PortPolicy is the subset of VMwareDVSPortSetting that we report on
+type PortPolicy struct {
+	Vlan                VlanInfo     `json:"vlan"`
+	Teaming             TeamingInfo  `json:"teaming"`
+	Security            SecurityInfo `json:"security"`
+	NetworkResourcePool string       `json:"networkResourcePool,omitempty"`
+}
+
+// LacpGroupInfo describes a Link Aggregation Group configured on the switch
+type LacpGroupInfo struct {
+	Name                 string   `json:"name"`
+	Mode                 string   `json:"mode"`
+	UplinkNum            int32    `json:"uplinkNum"`
+	LoadbalanceAlgorithm string   `json:"loadbalanceAlgorithm"`
+	TimeoutMode          string   `json:"timeoutMode,omitempty"`
+	UplinkNames          []string `json:"uplinkNames,omitempty"`
+}
+
+// NiocTrafficInfo is the NIOC allocation for one infrastructure traffic type
+type NiocTrafficInfo struct {
+	Key         string `json:"key"`
+	Limit       int64  `json:"limit"`
+	Reservation int64  `json:"reservation"`
+	SharesLevel string `json:"sharesLevel,omitempty"`
+	Shares      int32  `json:"shares,omitempty"`
+}
+
+// NiocInfo holds the Network I/O Control settings of the switch
+type NiocInfo struct {
+	Enabled bool              `json:"enabled"`
+	Version string            `json:"version,omitempty"`
+	Traffic []NiocTrafficInfo `json:"traffic,omitempty"`
+}
+
+// PvlanMapInfo is one entry of the switch private VLAN map
+type PvlanMapInfo struct {
+	PrimaryVlanID   int32  `json:"primaryVlanId"`
+	SecondaryVlanID int32  `json:"secondaryVlanId"`
+	PvlanType       string `json:"pvlanType"`
+}
+
+// SwitchInfo holds the report for a single distributed switch
+type SwitchInfo struct {
+	Ref            types.ManagedObjectReference `json:"-"`
+	Name           string                       `json:"name"`
+	Uuid           string                       `json:"uuid"`
+	Description    string                       `json:"description,omitempty"`
+	ConfigStatus   string                       `json:"configStatus"`
+	OverallStatus  string                       `json:"overallStatus"`
+	Version        string                       `json:"version"`
+	ConfigVersion  string                       `json:"configVersion"`
+	IPAddress      string                       `json:"ipAddress,omitempty"`
+	MaxMtu         int32                        `json:"maxMtu"`
+	NumPorts       int32                        `json:"numPorts"`
+	MaxPorts       int32                        `json:"maxPorts"`
+	NumHosts       int                          `json:"numHosts"`
+	Uplinks        []string                     `json:"uplinks,omitempty"`
+	LacpApiVersion string                       `json:"lacpApiVersion,omitempty"`
+	LacpGroups     []LacpGroupInfo              `json:"lacpGroups,omitempty"`
+	PvlanMap       []PvlanMapInfo               `json:"pvlanMap,omitempty"`
+	Nioc           NiocInfo                     `json:"nioc"`
+	DefaultPort    PortPolicy                   `json:"defaultPortConfig"`
+}
+
+// PortgroupInfo holds the report for a single distributed port group
+type PortgroupInfo struct {
+	Ref           types.ManagedObjectReference `json:"-"`
+	ConfigVersion string                       `json:"-"`
+	Name          string                       `json:"name"`
+	Key           string                       `json:"key"`
+	Description   string                       `json:"description,omitempty"`
+	Switch        string                       `json:"switch"`
+	Binding       string                       `json:"binding"`
+	Uplink        bool                         `json:"uplink"`
+	NumPorts      int32                        `json:"numPorts"`
+	PortsInUse    int                          `json:"portsInUse"`
+	AutoExpand    bool                         `json:"autoExpand"`
+	NumVMs        int                          `json:"numVms"`
+	Policy        PortPolicy                   `json:"policy"`
+}
+
+// Report is the top level document written with -json
+type Report struct {
+	Switches   []SwitchInfo    `json:"switches"`
+	Portgroups []PortgroupInfo `json:"portgroups"`
+}
+
+//
+// String renders the VLAN setting in the compact form used by the table output
+//
+
+func (v VlanInfo) String() string {
+	switch v.Type {
+	case "vlan":
+		if v.VlanID == nil {
+			return "vlan (no id)"
+		}
+		return fmt.Sprintf("%d", *v.VlanID)
+	case "trunk":
+		return "trunk " + strings.Join(v.TrunkRanges, ",")
+	case "pvlan":
+		if v.PvlanType != "" {
+			return fmt.Sprintf("pvlan %d (%s, primary %d)", v.PvlanID, v.PvlanType, v.PvlanPrimary)
+		}
+		return fmt.Sprintf("pvlan %d", v.PvlanID)
+	}
+	return v.Type
+}
+
+func boolValue(p *types.BoolPolicy) *bool {
+	if p == nil {
+		return nil
+	}
+	return p.Value
+}
+
+func boolString(b *bool) string {
+	if b == nil {
+		return "-"
+	}
+	if *b {
+		return "accept"
+	}
+	return "reject"
+}
+
+//
+// vlanInfo decodes the VLAN spec, which may be a single VLAN ID, a list of trunk ranges or a private VLAN ID.
+// The private VLAN ID is resolved against the switch PVLAN map to find its type and primary VLAN.
+//
+
+func vlanInfo(spec types.BaseVmwareDistributedVirtualSwitchVlanSpec, pvlans []types.VMwareDVSPvlanMapEntry) VlanInfo {
+	// gomvomi interface provides access to the underlying base type (VmwareDistributedVirtualSwitchVlanIdSpec)
+
+	switch vlan := spec.(type) {
+	case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
+		id := vlan.VlanId
+		return VlanInfo{Type: "vlan", VlanID: &id}
+	case *types.VmwareDistributedVirtualSwitchTrunkVlanSpec:
+		info := VlanInfo{Type: "trunk"}
+		for _, r := range vlan.VlanId {
+			if r.Start == r.End {
+				info.TrunkRanges = append(info.TrunkRanges, fmt.Sprintf("%d", r.Start))
+			} else {
+				info.TrunkRanges = append(info.TrunkRanges, fmt.Sprintf("%d-%d", r.Start, r.End))
+			}
+		}
+		return info
+	case *types.VmwareDistributedVirtualSwitchPvlanSpec:
+		info := VlanInfo{Type: "pvlan", PvlanID: vlan.PvlanId}
+		for _, e := range pvlans {
+			if e.SecondaryVlanId == vlan.PvlanId {
+				info.PvlanPrimary = e.PrimaryVlanId
+				info.PvlanType = e.PvlanType
+			}
+		}
+		return info
+	case nil:
+		return VlanInfo{Type: "none"}
+	default:
+		return VlanInfo{Type: fmt.Sprintf("%T", vlan)}
+	}
+}
+
+//
+// portPolicy pulls the VLAN, teaming, security and NIOC pool settings out of a port setting.
+// Distributed switches that are not VMware switches (or vcsim) may not return a VMwareDVSPortSetting at all.
+//
+
+func portPolicy(setting types.BaseDVPortSetting, pvlans []types.VMwareDVSPvlanMapEntry) PortPolicy {
+	var policy PortPolicy
+
+	// gomvomi interface provides access to the underlying base type (VMwareDVSPortSetting)
+
+	ps, ok := setting.(*types.VMwareDVSPortSetting)
+	if !ok || ps == nil {
+		policy.Vlan = VlanInfo{Type: "none"}
+		return policy
+	}
+
+	policy.Vlan = vlanInfo(ps.Vlan, pvlans)
+
+	if t := ps.UplinkTeamingPolicy; t != nil {
+		if t.Policy != nil {
+			policy.Teaming.Policy = t.Policy.Value
+		}
+		policy.Teaming.NotifySwitches = boolValue(t.NotifySwitches)
+
+		// Failback in the UI is the inverse of the rollingOrder flag in the API
+
+		if rolling := boolValue(t.RollingOrder); rolling != nil {
+			failback := !*rolling
+			policy.Teaming.Failback = &failback
+		}
+		if t.UplinkPortOrder != nil {
+			policy.Teaming.ActiveUplinks = t.UplinkPortOrder.ActiveUplinkPort
+			policy.Teaming.StandbyUplinks = t.UplinkPortOrder.StandbyUplinkPort
+		}
+	}
+
+	if s := ps.SecurityPolicy; s != nil {
+		policy.Security.AllowPromiscuous = boolValue(s.AllowPromiscuous)
+		policy.Security.MacChanges = boolValue(s.MacChanges)
+		policy.Security.ForgedTransmits = boolValue(s.ForgedTransmits)
+	}
+
+	if ps.NetworkResourcePoolKey != nil {
+		policy.NetworkResourcePool = ps.NetworkResourcePoolKey.Value
+	}
+
+	return policy
+}
+
+//
+// switchInfo builds the report for one DVS. The VMware specific fields (MTU, LACP, PVLAN) are only
+// present when the config is a VMwareDVSConfigInfo.
+//
+
+func switchInfo(s mo.DistributedVirtualSwitch) SwitchInfo {
+	info := SwitchInfo{
+		Ref:           s.Reference(),
+		Name:          s.Name,
+		ConfigStatus:  string(s.ConfigStatus),
+		OverallStatus: string(s.OverallStatus),
+	}
+
+	if s.Config == nil {
+		return info
+	}
+
+	base := s.Config.GetDVSConfigInfo()
+
+	info.Uuid = base.Uuid
+	info.Description = base.Description
+	info.Version = base.ProductInfo.Version
+	info.ConfigVersion = base.ConfigVersion
+	info.IPAddress = base.SwitchIpAddress
+	info.NumPorts = base.NumPorts
+	info.MaxPorts = base.MaxPorts
+	info.NumHosts = len(base.Host)
+
+	if uplinks, ok := base.UplinkPortPolicy.(*types.DVSNameArrayUplinkPortPolicy); ok {
+		info.Uplinks = uplinks.UplinkPortName
+	}
+
+	if base.NetworkResourceManagementEnabled != nil {
+		info.Nioc.Enabled = *base.NetworkResourceManagementEnabled
+	}
+	info.Nioc.Version = base.NetworkResourceControlVersion
+
+	for _, r := range base.InfrastructureTrafficResourceConfig {
+		t := NiocTrafficInfo{Key: r.Key}
+		if r.AllocationInfo.Limit != nil {
+			t.Limit = *r.AllocationInfo.Limit
+		}
+		if r.AllocationInfo.Reservation != nil {
+			t.Reservation = *r.AllocationInfo.Reservation
+		}
+		if r.AllocationInfo.Shares != nil {
+			t.SharesLevel = string(r.AllocationInfo.Shares.Level)
+			t.Shares = r.AllocationInfo.Shares.Shares
+		}
+		info.Nioc.Traffic = append(info.Nioc.Traffic, t)
+	}
+
+	var pvlans []types.VMwareDVSPvlanMapEntry
+
+	if config, ok := s.Config.(*types.VMwareDVSConfigInfo); ok {
+		pvlans = config.PvlanConfig
+		info.MaxMtu = config.MaxMtu
+		info.LacpApiVersion = config.LacpApiVersion
+
+		for _, l := range config.LacpGroupConfig {
+			info.LacpGroups = append(info.LacpGroups, LacpGroupInfo{
+				Name:                 l.Name,
+				Mode:                 l.Mode,
+				UplinkNum:            l.UplinkNum,
+				LoadbalanceAlgorithm: l.LoadbalanceAlgorithm,
+				TimeoutMode:          l.TimeoutMode,
+				UplinkNames:          l.UplinkName,
+			})
+		}
+
+		for _, p := range config.PvlanConfig {
+			info.PvlanMap = append(info.PvlanMap, PvlanMapInfo{
+				PrimaryVlanID:   p.PrimaryVlanId,
+				SecondaryVlanID: p.SecondaryVlanId,
+				PvlanType:       p.PvlanType,
+			})
+		}
+	}
+
+	info.DefaultPort = portPolicy(base.DefaultPortConfig, pvlans)
+
+	return info
+}
+
+func printTable(report Report) {
+	tw := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+
+	fmt.Printf("\n*** Distributed Switch Information ***\n")
+	fmt.Printf("--------------------------------------\n\n")
+
+	for _, s := range report.Switches {
+		fmt.Fprintf(tw, "DVS Name:\t%s\n", s.Name)
+		fmt.Fprintf(tw, "Config Status:\t%s\n", s.ConfigStatus)
+		fmt.Fprintf(tw, "Overall Status:\t%s\n", s.OverallStatus)
+		fmt.Fprintf(tw, "Version:\t%s\n", s.Version)
+		fmt.Fprintf(tw, "Config Version:\t%s\n", s.ConfigVersion)
+		fmt.Fprintf(tw, "IP Address:\t%s\n", s.IPAddress)
+		fmt.Fprintf(tw, "MTU:\t%d\n", s.MaxMtu)
+		fmt.Fprintf(tw, "Ports (configured/max):\t%d/%d\n", s.NumPorts, s.MaxPorts)
+		fmt.Fprintf(tw, "Hosts:\t%d\n", s.NumHosts)
+		fmt.Fprintf(tw, "Uplinks:\t%s\n", strings.Join(s.Uplinks, ", "))
+		fmt.Fprintf(tw, "Default VLAN:\t%s\n", s.DefaultPort.Vlan)
+		fmt.Fprintf(tw, "Default Teaming:\t%s\n", s.DefaultPort.Teaming.Policy)
+		fmt.Fprintf(tw, "NIOC:\tenabled=%t version=%s\n", s.Nioc.Enabled, s.Nioc.Version)
+		_ = tw.Flush()
+
+		if len(s.Nioc.Traffic) > 0 {
+			fmt.Fprintf(tw, "\n\tTraffic\tShares\tReservation(Mbit/s)\tLimit(Mbit/s)\n")
+			for _, t := range s.Nioc.Traffic {
+				fmt.Fprintf(tw, "\t%s\t%s/%d\t%d\t%d\n", t.Key, t.SharesLevel, t.Shares, t.Reservation, t.Limit)
+			}
+			_ = tw.Flush()
+		}
+
+		if len(s.LacpGroups) > 0 {
+			fmt.Fprintf(tw, "\n\tLAG\tMode\tUplinks\tLoad Balancing\tTimeout\n")
+			for _, l := range s.LacpGroups {
+				fmt.Fprintf(tw, "\t%s\t%s\t%d\t%s\t%s\n", l.Name, l.Mode, l.UplinkNum, l.LoadbalanceAlgorithm, l.TimeoutMode)
+			}
+			_ = tw.Flush()
+		}
+
+		if len(s.PvlanMap) > 0 {
+			fmt.Fprintf(tw, "\n\tPrimary VLAN\tSecondary VLAN\tPVLAN Type\n")
+			for _, p := range s.PvlanMap {
+				fmt.Fprintf(tw, "\t%d\t%d\t%s\n", p.PrimaryVlanID, p.SecondaryVlanID, p.PvlanType)
+			}
+			_ = tw.Flush()
+		}
+
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("\n*** Distributed Port Group Information ***\n")
+	fmt.Printf("------------------------------------------\n\n")
+
+	fmt.Fprintf(tw, "Name\tDVS\tVLAN\tBinding\tPorts\tIn Use\tVMs\tTeaming\tActive\tStandby\tPromisc\tMAC Chg\tForged\tNIOC Pool\n")
+	fmt.Fprintf(tw, "----\t---\t----\t-------\t-----\t------\t---\t-------\t------\t-------\t-------\t-------\t------\t---------\n")
+
+	for _, pg := range report.Portgroups {
+		name := pg.Name
+		if pg.Uplink {
+			name += " (uplink)"
+		}
+		fmt.Fprintf(tw, "%s\t", name)
+		fmt.Fprintf(tw, "%s\t", pg.Switch)
+		fmt.Fprintf(tw, "%s\t", pg.Policy.Vlan)
+		fmt.Fprintf(tw, "%s\t", pg.Binding)
+		fmt.Fprintf(tw, "%d\t", pg.NumPorts)
+		fmt.Fprintf(tw, "%d\t", pg.PortsInUse)
+		fmt.Fprintf(tw, "%d\t", pg.NumVMs)
+		fmt.Fprintf(tw, "%s\t", pg.Policy.Teaming.Policy)
+		fmt.Fprintf(tw, "%s\t", strings.Join(pg.Policy.Teaming.ActiveUplinks, ","))
+		fmt.Fprintf(tw, "%s\t", strings.Join(pg.Policy.Teaming.StandbyUplinks, ","))
+		fmt.Fprintf(tw, "%s\t", boolString(pg.Policy.Security.AllowPromiscuous))
+		fmt.Fprintf(tw, "%s\t", boolString(pg.Policy.Security.MacChanges))
+		fmt.Fprintf(tw, "%s\t", boolString(pg.Policy.Security.ForgedTransmits))
+		fmt.Fprintf(tw, "%s\n", pg.Policy.NetworkResourcePool)
+	}
+
+	fmt.Fprintf(tw, "\n")
+	_ = tw.Flush()
+}
+
+// DVSDocument is the portable switch configuration written by -export and read by -import
+type DVSDocument struct {
+	Switches []SwitchSpec `json:"switches"`
+}
+
+// SwitchSpec is the portable part of a distributed switch - anything tied to one vCenter (hosts, keys, MoRefs) is left out.
+// The pointer fields tell a setting left out of the document (left alone on import) from one set to false, 0 or empty,
+// e.g. a document without a pvlanMap leaves the switch PVLANs alone, while "pvlanMap: []" clears them.
+type SwitchSpec struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version,omitempty"`
+	Description string          `json:"description,omitempty"`
+	MaxMtu      int32           `json:"maxMtu,omitempty"`
+	Uplinks     []string        `json:"uplinks,omitempty"`
+	NiocEnabled *bool           `json:"niocEnabled,omitempty"`
+	PvlanMap    *[]PvlanMapInfo `json:"pvlanMap,omitempty"`
+	DefaultPort PortPolicy      `json:"defaultPortConfig"`
+	Portgroups  []PortgroupSpec `json:"portgroups,omitempty"`
+}
+
+// PortgroupSpec is the portable part of a distributed port group
+type PortgroupSpec struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Binding     *string    `json:"binding,omitempty"`
+	NumPorts    *int32     `json:"numPorts,omitempty"`
+	AutoExpand  *bool      `json:"autoExpand,omitempty"`
+	Policy      PortPolicy `json:"policy"`
+}
+
+func switchSpec(sw SwitchInfo) SwitchSpec {
+	pvlans := append([]PvlanMapInfo{}, sw.PvlanMap...)
+
+	spec := SwitchSpec{
+		Name:        sw.Name,
+		Version:     sw.Version,
+		Description: sw.Description,
+		MaxMtu:      sw.MaxMtu,
+		Uplinks:     sw.Uplinks,
+		NiocEnabled: types.NewBool(sw.Nioc.Enabled),
+		PvlanMap:    &pvlans,
+		DefaultPort: sw.DefaultPort,
+	}
+
+	// NIOC pool keys are generated by each vCenter
+
+	spec.DefaultPort.NetworkResourcePool = ""
+
+	return spec
+}
+
+func portgroupSpec(pg PortgroupInfo) PortgroupSpec {
+	binding := pg.Binding
+
+	spec := PortgroupSpec{
+		Name:        pg.Name,
+		Description: pg.Description,
+		Binding:     &binding,
+		NumPorts:    types.NewInt32(pg.NumPorts),
+		AutoExpand:  types.NewBool(pg.AutoExpand),
+		Policy:      pg.Policy,
+	}
+	spec.Policy.NetworkResourcePool = ""
+
+	return spec
+}
+
+//
+// exportDocument turns the report into the portable document. Uplink port groups are created by vCenter
+// along with the switch, so only the uplink names are kept.
+//
+
+func exportDocument(report Report, only string) DVSDocument {
+	var doc DVSDocument
+
+	for _, sw := range report.Switches {
+		if only != "" && sw.Name != only {
+			continue
+		}
+		spec := switchSpec(sw)
+		for _, pg := range report.Portgroups {
+			if pg.Switch == sw.Name && !pg.Uplink {
+				spec.Portgroups = append(spec.Portgroups, portgroupSpec(pg))
+			}
+		}
+		doc.Switches = append(doc.Switches, spec)
+	}
+
+	return doc
+}
+
+//
+// filterReport keeps the tagged switches and port groups. Every port group of a tagged switch is kept, and a
+// switch is kept (for context) when any of its port groups is tagged.
+//
+
+func filterReport(report Report, filter *tagFilter) Report {
+	if len(filter.tags) == 0 {
+		return report
+	}
+
+	switchTagged := make(map[string]bool)
+	for _, sw := range report.Switches {
+		switchTagged[sw.Name] = filter.keep(sw.Ref)
+	}
+
+	var filtered Report
+	hasPortgroups := make(map[string]bool)
+
+	for _, pg := range report.Portgroups {
+		if switchTagged[pg.Switch] || filter.keep(pg.Ref) {
+			filtered.Portgroups = append(filtered.Portgroups, pg)
+			hasPortgroups[pg.Switch] = true
+		}
+	}
+
+	for _, sw := range report.Switches {
+		if switchTagged[sw.Name] || hasPortgroups[sw.Name] {
+			filtered.Switches = append(filtered.Switches, sw)
+		}
+	}
+
+	return filtered
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func writeDocument(path string, doc DVSDocument) error {
+	var data []byte
+	var err error
+
+	if isYAML(path) {
+		data, err = yaml.Marshal(doc)
+	} else {
+		data, err = json.MarshalIndent(doc, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d switch(es) to %s\n", len(doc.Switches), path)
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+//
+// readDocument accepts either format - JSON is valid YAML, and the YAML is converted to JSON so the json tags apply
+//
+
+func readDocument(path string) (DVSDocument, error) {
+	var doc DVSDocument
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return doc, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &doc)
+	if err != nil {
+		return doc, err
+	}
+
+	// Check every VLAN and teaming setting up front, rather than part way through an -apply
+
+	for _, sw := range doc.Switches {
+		if _, err = switchConfigSpec(sw, nil); err != nil {
+			return doc, err
+		}
+		for _, pg := range sw.Portgroups {
+			if _, err = portgroupConfigSpec(pg, ""); err != nil {
+				return doc, fmt.Errorf("switch %s: %s", sw.Name, err)
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+//
+// flatten walks a value through its JSON form and records every leaf as "path" -> "value",
+// which gives a simple line by line diff of two specs. The path of every list is recorded too.
+//
+
+func flatten(prefix string, v interface{}, out map[string]string, lists map[string]bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if prefix == "" {
+				flatten(k, val, out, lists)
+			} else {
+				flatten(prefix+"."+k, val, out, lists)
+			}
+		}
+	case []interface{}:
+		lists[prefix] = true
+		for i, val := range t {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), val, out, lists)
+		}
+	default:
+		out[prefix] = fmt.Sprintf("%v", t)
+	}
+}
+
+func leaves(v interface{}) (map[string]string, map[string]bool) {
+	out := make(map[string]string)
+	lists := make(map[string]bool)
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return out, lists
+	}
+
+	var generic interface{}
+	if json.Unmarshal(data, &generic) == nil {
+		flatten("", generic, out, lists)
+	}
+
+	return out, lists
+}
+
+// inList reports whether key is an element (or part of an element) of one of the lists
+func inList(key string, lists map[string]bool) bool {
+	for i := strings.Index(key, "["); i >= 0; {
+		if lists[key[:i]] {
+			return true
+		}
+		j := strings.Index(key[i+1:], "[")
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return false
+}
+
+//
+// diffSpecs lists the settings that differ between current and desired. A list in the document replaces the
+// live list on apply, so entries it drops show as "(unset)". Any other setting that is absent from the document
+// is left alone on apply, and is not reported.
+//
+
+func diffSpecs(current, desired interface{}) []string {
+	have, _ := leaves(current)
+	want, wantLists := leaves(desired)
+
+	var keys []string
+	for k := range want {
+		keys = append(keys, k)
+	}
+	for k := range have {
+		if _, ok := want[k]; !ok && inList(k, wantLists) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, k := range keys {
+		old, ok := have[k]
+		wanted, set := want[k]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s: (unset) -> %s", k, wanted))
+		case !set:
+			changes = append(changes, fmt.Sprintf("%s: %s -> (unset)", k, old))
+		case old != wanted:
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", k, old, wanted))
+		}
+	}
+
+	return changes
+}
+
+func boolPolicy(b *bool) *types.BoolPolicy {
+	if b == nil {
+		return nil
+	}
+	return &types.BoolPolicy{Value: types.NewBool(*b)}
+}
+
+//
+// portSetting is the reverse of portPolicy - it builds the VMwareDVSPortSetting for a create or reconfigure spec.
+// Only the settings present in the document are filled in, vCenter leaves the others as they are.
+//
+
+func portSetting(policy PortPolicy) (*types.VMwareDVSPortSetting, error) {
+	setting := new(types.VMwareDVSPortSetting)
+
+	switch policy.Vlan.Type {
+	case "vlan":
+		if policy.Vlan.VlanID == nil {
+			return nil, fmt.Errorf("vlan type \"vlan\" requires a vlanId")
+		}
+		setting.Vlan = &types.VmwareDistributedVirtualSwitchVlanIdSpec{VlanId: *policy.Vlan.VlanID}
+	case "trunk":
+		var ranges []types.NumericRange
+		for _, r := range policy.Vlan.TrunkRanges {
+			var start, end int32
+			if n, _ := fmt.Sscanf(r, "%d-%d", &start, &end); n == 1 {
+				end = start
+			} else if n != 2 {
+				return nil, fmt.Errorf("invalid trunk range %q", r)
+			}
+			ranges = append(ranges, types.NumericRange{Start: start, End: end})
+		}
+		setting.Vlan = &types.VmwareDistributedVirtualSwitchTrunkVlanSpec{VlanId: ranges}
+	case "pvlan":
+		setting.Vlan = &types.VmwareDistributedVirtualSwitchPvlanSpec{PvlanId: policy.Vlan.PvlanID}
+	case "", "none":
+	default:
+		return nil, fmt.Errorf("unsupported vlan type %q", policy.Vlan.Type)
+	}
+
+	t := policy.Teaming
+	if t.Policy != "" || t.NotifySwitches != nil || t.Failback != nil || len(t.ActiveUplinks) > 0 || len(t.StandbyUplinks) > 0 {
+		teaming := &types.VmwareUplinkPortTeamingPolicy{
+			NotifySwitches: boolPolicy(t.NotifySwitches),
+		}
+		if t.Policy != "" {
+			teaming.Policy = &types.StringPolicy{Value: t.Policy}
+		}
+		if t.Failback != nil {
+			teaming.RollingOrder = boolPolicy(types.NewBool(!*t.Failback))
+		}
+		if len(t.ActiveUplinks) > 0 || len(t.StandbyUplinks) > 0 {
+			teaming.UplinkPortOrder = &types.VMwareUplinkPortOrderPolicy{
+				ActiveUplinkPort:  t.ActiveUplinks,
+				StandbyUplinkPort: t.StandbyUplinks,
+			}
+		}
+		setting.UplinkTeamingPolicy = teaming
+	}
+
+	sec := policy.Security
+	if sec.AllowPromiscuous != nil || sec.MacChanges != nil || sec.ForgedTransmits != nil {
+		setting.SecurityPolicy = &types.DVSSecurityPolicy{
+			AllowPromiscuous: boolPolicy(sec.AllowPromiscuous),
+			MacChanges:       boolPolicy(sec.MacChanges),
+			ForgedTransmits:  boolPolicy(sec.ForgedTransmits),
+		}
+	}
+
+	return setting, nil
+}
+
+//
+// switchConfigSpec builds the create (live == nil) or reconfigure spec of a switch.
+// When the document has a pvlanMap, PVLAN map entries are added or removed so the map matches it.
+//
+
+func switchConfigSpec(want SwitchSpec, live *SwitchInfo) (*types.VMwareDVSConfigSpec, error) {
+	setting, err := portSetting(want.DefaultPort)
+	if err != nil {
+		return nil, fmt.Errorf("switch %s: %s", want.Name, err)
+	}
+
+	spec := &types.VMwareDVSConfigSpec{
+		DVSConfigSpec: types.DVSConfigSpec{
+			Name:              want.Name,
+			Description:       want.Description,
+			DefaultPortConfig: setting,
+		},
+		MaxMtu: want.MaxMtu,
+	}
+
+	if len(want.Uplinks) > 0 {
+		spec.UplinkPortPolicy = &types.DVSNameArrayUplinkPortPolicy{UplinkPortName: want.Uplinks}
+	}
+
+	if live != nil {
+		spec.ConfigVersion = live.ConfigVersion
+	}
+
+	if want.PvlanMap == nil {
+		return spec, nil
+	}
+
+	existing := make(map[PvlanMapInfo]bool)
+	wanted := make(map[PvlanMapInfo]bool)
+
+	if live != nil {
+		for _, p := range live.PvlanMap {
+			existing[p] = true
+		}
+	}
+
+	for _, p := range *want.PvlanMap {
+		wanted[p] = true
+		if !existing[p] {
+			spec.PvlanConfigSpec = append(spec.PvlanConfigSpec, types.VMwareDVSPvlanConfigSpec{
+				PvlanEntry: types.VMwareDVSPvlanMapEntry{PrimaryVlanId: p.PrimaryVlanID, SecondaryVlanId: p.SecondaryVlanID, PvlanType: p.PvlanType},
+				Operation:  string(types.ConfigSpecOperationAdd),
+			})
+		}
+	}
+
+	if live != nil {
+		for _, p := range live.PvlanMap {
+			if !wanted[p] {
+				spec.PvlanConfigSpec = append(spec.PvlanConfigSpec, types.VMwareDVSPvlanConfigSpec{
+					PvlanEntry: types.VMwareDVSPvlanMapEntry{PrimaryVlanId: p.PrimaryVlanID, SecondaryVlanId: p.SecondaryVlanID, PvlanType: p.PvlanType},
+					Operation:  string(types.ConfigSpecOperationRemove),
+				})
+			}
+		}
+	}
+
+	return spec, nil
+}
+
+func portgroupConfigSpec(want PortgroupSpec, configVersion string) (types.DVPortgroupConfigSpec, error) {
+	setting, err := portSetting(want.Policy)
+	if err != nil {
+		return types.DVPortgroupConfigSpec{}, fmt.Errorf("portgroup %s: %s", want.Name, err)
+	}
+
+	spec := types.DVPortgroupConfigSpec{
+		ConfigVersion:     configVersion,
+		Name:              want.Name,
+		Description:       want.Description,
+		AutoExpand:        want.AutoExpand,
+		DefaultPortConfig: setting,
+	}
+
+	// A new port group needs a binding type, an existing one keeps its own when the document has none
+
+	if want.Binding != nil {
+		spec.Type = *want.Binding
+	} else if configVersion == "" {
+		spec.Type = string(types.DistributedVirtualPortgroupPortgroupTypeEarlyBinding)
+	}
+	if want.NumPorts != nil {
+		spec.NumPorts = *want.NumPorts
+	}
+
+	return spec, nil
+}
+
+func setNioc(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference, enable bool) error {
+	_, err := methods.EnableNetworkResourceManagement(ctx, c, &types.EnableNetworkResourceManagement{
+		This:   ref,
+		Enable: enable,
+	})
+	return err
+}
+
+func createSwitch(ctx context.Context, c *vim25.Client, datacenter string, want SwitchSpec) (types.ManagedObjectReference, error) {
+	var ref types.ManagedObjectReference
+
+	finder := find.NewFinder(c, true)
+
+	dc, err := finder.DatacenterOrDefault(ctx, datacenter)
+	if err != nil {
+		return ref, err
+	}
+
+	folders, err := dc.Folders(ctx)
+	if err != nil {
+		return ref, err
+	}
+
+	config, err := switchConfigSpec(want, nil)
+	if err != nil {
+		return ref, err
+	}
+
+	create := types.DVSCreateSpec{ConfigSpec: config}
+	if want.Version != "" {
+		create.ProductInfo = &types.DistributedVirtualSwitchProductSpec{Version: want.Version}
+	}
+
+	task, err := folders.NetworkFolder.CreateDVS(ctx, create)
+	if err != nil {
+		return ref, err
+	}
+
+	info, err := task.WaitForResult(ctx, nil)
+	if err != nil {
+		return ref, err
+	}
+
+	ref = info.Result.(types.ManagedObjectReference)
+
+	if want.NiocEnabled != nil && *want.NiocEnabled {
+		err = setNioc(ctx, c, ref, true)
+	}
+
+	return ref, err
+}
+
+func addPortgroups(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference, pgs []PortgroupSpec) error {
+	var specs []types.DVPortgroupConfigSpec
+
+	for _, pg := range pgs {
+		spec, err := portgroupConfigSpec(pg, "")
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return nil
+	}
+
+	task, err := object.NewDistributedVirtualSwitch(c, ref).AddPortgroup(ctx, specs)
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+//
+// importDocument compares the document with what is in vCenter and prints the plan:
 //
-////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//   + will be created
+//   ~ will be reconfigured, followed by the settings that change
+//   ! needs attention but is not changed by -apply
 //
+// With apply set, each step is carried out as it is printed.
 //
 
-package main
+func importDocument(ctx context.Context, c *vim25.Client, path, datacenter, only string, apply bool) error {
+	doc, err := readDocument(path)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %s", path, err)
+	}
 
-import (
-	"context"
-	"flag"
-	"fmt"
-	"net/url"
-	"os"
+	report, err := collect(ctx, c)
+	if err != nil {
+		return err
+	}
 
-	"github.com/vmware/govmomi/vim25"
-	"github.com/vmware/govmomi/vim25/mo"
-	"github.com/vmware/govmomi/vim25/soap"
-	"github.com/vmware/govmomi/vim25/types"
-	"github.com/vmware/govmomi/view"
-	"github.com/vmware/govmomi/session/cache"
-)
+	liveSwitches := make(map[string]SwitchInfo)
+	for _, sw := range report.Switches {
+		liveSwitches[sw.Name] = sw
+	}
+
+	livePortgroups := make(map[string]map[string]PortgroupInfo)
+	for _, pg := range report.Portgroups {
+		if livePortgroups[pg.Switch] == nil {
+			livePortgroups[pg.Switch] = make(map[string]PortgroupInfo)
+		}
+		livePortgroups[pg.Switch][pg.Name] = pg
+	}
+
+	for _, want := range doc.Switches {
+		if only != "" && want.Name != only {
+			continue
+		}
+
+		live, exists := liveSwitches[want.Name]
+
+		if !exists {
+			fmt.Printf("+ switch %s\n", want.Name)
+			for _, pg := range want.Portgroups {
+				fmt.Printf("  + portgroup %s (vlan %s)\n", pg.Name, pg.Policy.Vlan)
+			}
+
+			if apply {
+				ref, err := createSwitch(ctx, c, datacenter, want)
+				if err != nil {
+					return fmt.Errorf("could not create switch %s: %s", want.Name, err)
+				}
+				if err = addPortgroups(ctx, c, ref, want.Portgroups); err != nil {
+					return fmt.Errorf("could not create port groups on %s: %s", want.Name, err)
+				}
+			}
+			continue
+		}
+
+		//
+		// Switch level settings - port groups are compared separately, and a version
+		// change is a switch upgrade rather than a reconfigure, so it is only flagged
+		//
+
+		current := switchSpec(live)
+		desired := want
+		desired.Portgroups = nil
+
+		if desired.Version != "" && desired.Version != current.Version {
+			fmt.Printf("! switch %s: version %s -> %s needs a switch upgrade, not changed\n", want.Name, current.Version, desired.Version)
+		}
+		current.Version = ""
+		desired.Version = ""
+
+		changes := diffSpecs(current, desired)
+
+		if len(changes) == 0 {
+			fmt.Printf("= switch %s\n", want.Name)
+		} else {
+			fmt.Printf("~ switch %s\n", want.Name)
+			for _, change := range changes {
+				fmt.Printf("    %s\n", change)
+			}
+
+			if apply {
+				spec, err := switchConfigSpec(want, &live)
+				if err != nil {
+					return err
+				}
+				task, err := object.NewDistributedVirtualSwitch(c, live.Ref).Reconfigure(ctx, spec)
+				if err == nil {
+					err = task.Wait(ctx)
+				}
+				if err != nil {
+					return fmt.Errorf("could not reconfigure switch %s: %s", want.Name, err)
+				}
+				if want.NiocEnabled != nil && *want.NiocEnabled != live.Nioc.Enabled {
+					if err = setNioc(ctx, c, live.Ref, *want.NiocEnabled); err != nil {
+						return fmt.Errorf("could not change NIOC on switch %s: %s", want.Name, err)
+					}
+				}
+			}
+		}
+
+		//
+		// Port groups
+		//
+
+		var missing []PortgroupSpec
+		seen := make(map[string]bool)
+
+		for _, pg := range want.Portgroups {
+			seen[pg.Name] = true
+
+			livePG, ok := livePortgroups[want.Name][pg.Name]
+			if !ok {
+				fmt.Printf("  + portgroup %s (vlan %s)\n", pg.Name, pg.Policy.Vlan)
+				missing = append(missing, pg)
+				continue
+			}
+
+			// An auto expanding port group grows on its own, so its port count is not a difference
+			// as long as the document keeps (or leaves alone) auto expand
+
+			currentPG := portgroupSpec(livePG)
+			desiredPG := pg
+			if livePG.AutoExpand && (desiredPG.AutoExpand == nil || *desiredPG.AutoExpand) {
+				currentPG.NumPorts = nil
+				desiredPG.NumPorts = nil
+			}
+
+			changes := diffSpecs(currentPG, desiredPG)
+			if len(changes) == 0 {
+				fmt.Printf("  = portgroup %s\n", pg.Name)
+				continue
+			}
+
+			fmt.Printf("  ~ portgroup %s\n", pg.Name)
+			for _, change := range changes {
+				fmt.Printf("      %s\n", change)
+			}
+
+			if apply {
+				spec, err := portgroupConfigSpec(pg, livePG.ConfigVersion)
+				if err != nil {
+					return err
+				}
+				task, err := object.NewDistributedVirtualPortgroup(c, livePG.Ref).Reconfigure(ctx, spec)
+				if err == nil {
+					err = task.Wait(ctx)
+				}
+				if err != nil {
+					return fmt.Errorf("could not reconfigure portgroup %s: %s", pg.Name, err)
+				}
+			}
+		}
+
+		for name, pg := range livePortgroups[want.Name] {
+			if !seen[name] && !pg.Uplink {
+				fmt.Printf("  ! portgroup %s is not in %s, left in place\n", name, path)
+			}
+		}
+
+		if apply {
+			if err = addPortgroups(ctx, c, live.Ref, missing); err != nil {
+				return fmt.Errorf("could not create port groups on %s: %s", want.Name, err)
+			}
+		}
+	}
+
+	if !apply {
+		fmt.Printf("\nNo changes made, re-run with -apply to carry out the plan above\n")
+	}
+
+	return nil
+}
+
+//
+// collect discovers every distributed switch and port group and builds the report used by the table,
+// -json and -export outputs
+//
+
+func collect(ctx context.Context, c *vim25.Client) (Report, error) {
+	// Create a view of DVS Network objects
+
+	m := view.NewManager(c)
+
+	var report Report
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualSwitch"}, true)
+	if err != nil {
+		return report, fmt.Errorf("could not create DVS container view: %s", err)
+	}
+
+	defer v.Destroy(ctx)
+
+	// Retrieve the properties we report on for all DVS
+	// Use 'govc object.collect network/DVS-Name' to see available fields to retrieve
+
+	var vds []mo.DistributedVirtualSwitch
+	err = v.Retrieve(ctx, []string{"DistributedVirtualSwitch"}, []string{"name", "config", "configStatus", "overallStatus"}, &vds)
+	if err != nil {
+		return report, fmt.Errorf("could not retrieve DVS info: %s", err)
+	}
+
+	//
+	// Keep the PVLAN map and name of each switch, the port groups only hold a reference to their switch
+	//
+
+	switchNames := make(map[types.ManagedObjectReference]string)
+	switchPvlans := make(map[types.ManagedObjectReference][]types.VMwareDVSPvlanMapEntry)
+
+	//
+	// Count the connected ports of each port group - FetchDVPorts is a per switch call, so make it once
+	// per switch with a "connected" criteria and bucket the ports by port group key
+	//
+
+	connected := true
+	portsInUse := make(map[string]int)
+	uplinkPortgroups := make(map[types.ManagedObjectReference]bool)
+
+	// Build the report per DVS
+
+	for _, sw := range vds {
+		switchNames[sw.Reference()] = sw.Name
+		// gomvomi interface provides access to the underlying base type (VMwareDVSConfigInfo)
+		if config, ok := sw.Config.(*types.VMwareDVSConfigInfo); ok {
+			switchPvlans[sw.Reference()] = config.PvlanConfig
+		}
+		if sw.Config != nil {
+			for _, ref := range sw.Config.GetDVSConfigInfo().UplinkPortgroup {
+				uplinkPortgroups[ref] = true
+			}
+		}
+		report.Switches = append(report.Switches, switchInfo(sw))
+
+		dvs := object.NewDistributedVirtualSwitch(c, sw.Reference())
+		ports, err := dvs.FetchDVPorts(ctx, &types.DistributedVirtualSwitchPortCriteria{Connected: &connected})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error : could not fetch ports of DVS %s: %s\n", sw.Name, err)
+			continue
+		}
+		for _, port := range ports {
+			portsInUse[port.PortgroupKey]++
+		}
+	}
+
+	//
+	// Turning our attention to the distributed port groups, create a view of DVS PG Network objects
+	//
+
+	v1, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualPortgroup"}, true)
+	if err != nil {
+		return report, fmt.Errorf("could not create DVS PG container view: %s", err)
+	}
+
+	defer v1.Destroy(ctx)
+
+	// Retrieve the properties we report on for all DVS-PG
+	// Use 'govc object.collect /DC/network/DVPG-Name' to see available fields to retrieve
+
+	var vdspg []mo.DistributedVirtualPortgroup
+	err = v1.Retrieve(ctx, []string{"DistributedVirtualPortgroup"}, []string{"name", "key", "config", "vm"}, &vdspg)
+	if err != nil {
+		return report, fmt.Errorf("could not retrieve DVS PG info: %s", err)
+	}
+
+	// Build the report per DVS-PG
+
+	for _, pg := range vdspg {
+		info := PortgroupInfo{
+			Ref:           pg.Reference(),
+			ConfigVersion: pg.Config.ConfigVersion,
+			Name:          pg.Name,
+			Key:           pg.Key,
+			Description:   pg.Config.Description,
+			Binding:       pg.Config.Type,
+			NumPorts:      pg.Config.NumPorts,
+			PortsInUse:    portsInUse[pg.Key],
+			NumVMs:        len(pg.Vm),
+		}
+
+		if pg.Config.Uplink != nil {
+			info.Uplink = *pg.Config.Uplink
+		}
+		if uplinkPortgroups[pg.Reference()] {
+			info.Uplink = true
+		}
+		if pg.Config.AutoExpand != nil {
+			info.AutoExpand = *pg.Config.AutoExpand
+		}
+
+		var pvlans []types.VMwareDVSPvlanMapEntry
+		if pg.Config.DistributedVirtualSwitch != nil {
+			info.Switch = switchNames[*pg.Config.DistributedVirtualSwitch]
+			pvlans = switchPvlans[*pg.Config.DistributedVirtualSwitch]
+		}
+
+		//
+		// Uplink port groups normally carry trunk VLANs, these are decoded like any other VLAN spec
+		//
+
+		info.Policy = portPolicy(pg.Config.DefaultPortConfig, pvlans)
+
+		report.Portgroups = append(report.Portgroups, info)
+	}
+
+	sort.Slice(report.Switches, func(i, j int) bool { return report.Switches[i].Name < report.Switches[j].Name })
+	sort.Slice(report.Portgroups, func(i, j int) bool {
+		if report.Portgroups[i].Switch != report.Portgroups[j].Switch {
+			return report.Portgroups[i].Switch < report.Portgroups[j].Switch
+		}
+		return report.Portgroups[i].Name < report.Portgroups[j].Name
+	})
+
+	return report, nil
+}
+
+//
+// tagFilter holds the -tag category:name values. With -tag-match all (the default) an object must carry every
+// tag, with -tag-match any it needs at least one of them.
+//
+
+type tagFilter struct {
+	tags    []string
+	match   string
+	objects map[types.ManagedObjectReference]bool
+}
+
+func (f *tagFilter) String() string { return strings.Join(f.tags, ",") }
+
+func (f *tagFilter) Set(value string) error {
+	if i := strings.Index(value, ":"); i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("want category:name, got %q", value)
+	}
+	f.tags = append(f.tags, value)
+	return nil
+}
+
+//
+// resolve finds the objects that pass the filter. Tags live behind the vAPI (REST) endpoint, which needs its own
+// login, and GetAttachedObjectsOnTags returns the objects for all of the tags in one call.
+//
+
+func (f *tagFilter) resolve(ctx context.Context, c *vim25.Client, user *url.Userinfo) error {
+	if len(f.tags) == 0 {
+		return nil
+	}
+	if f.match != "all" && f.match != "any" {
+		return fmt.Errorf("-tag-match must be \"all\" or \"any\", not %q", f.match)
+	}
+
+	rc := rest.NewClient(c)
+	if err := rc.Login(ctx, user); err != nil {
+		return fmt.Errorf("could not log in to the tagging service: %s", err)
+	}
+	defer rc.Logout(ctx)
+
+	m := tags.NewManager(rc)
+
+	var ids []string
+	for _, name := range f.tags {
+		i := strings.Index(name, ":")
+		tag, err := m.GetTagForCategory(ctx, name[i+1:], name[:i])
+		if err != nil {
+			return fmt.Errorf("could not find tag %s: %s", name, err)
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	attached, err := m.GetAttachedObjectsOnTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("could not get the objects tagged %s: %s", f, err)
+	}
+
+	count := make(map[types.ManagedObjectReference]int)
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			count[obj.Reference()]++
+		}
+	}
+
+	f.objects = make(map[types.ManagedObjectReference]bool)
+	for ref, n := range count {
+		if f.match == "any" || n == len(ids) {
+			f.objects[ref] = true
+		}
+	}
+
+	return nil
+}
+
+// keep reports whether an object passes the filter - everything passes when no -tag was given
+func (f *tagFilter) keep(ref types.ManagedObjectReference) bool {
+	return len(f.tags) == 0 || f.objects[ref]
+}
 
 func main() {
 	//
@@ -38,43 +1391,50 @@ func main() {
 	// GOVMOMI_USERNAME
 	// GOVMOMI_PASSWORD
 	//
+	// Progress messages go to stderr so that -json output on stdout can be piped to other tools
+	//
+
+	var jsonOutput, apply bool
+	var exportFile, importFile, datacenter, switchName string
+	flag.BoolVar(&jsonOutput, "json", false, "write the report as JSON instead of a table")
+	flag.StringVar(&exportFile, "export", "", "export the DVS configuration to this file (.yaml/.yml for YAML, otherwise JSON, - for stdout)")
+	flag.StringVar(&importFile, "import", "", "show the changes needed to make vCenter match this DVS configuration file")
+	flag.BoolVar(&apply, "apply", false, "with -import, create or reconfigure the switches and port groups")
+	flag.StringVar(&datacenter, "datacenter", "", "with -import, datacenter in which to create missing switches (default datacenter if empty)")
+	flag.StringVar(&switchName, "switch", "", "only export or import the switch with this name")
+
+	var filter tagFilter
+	flag.Var(&filter, "tag", "only report or export switches and port groups with this category:name tag (repeat for more than one tag)")
+	flag.StringVar(&filter.match, "tag-match", "all", "with more than one -tag, objects must have \"all\" of the tags or \"any\" of them")
+	flag.Parse()
 
 	vc := os.Getenv("GOVMOMI_URL")
 
 	if len(vc) > 0 {
-		fmt.Printf("DEBUG: vc is %s\n", vc)
+		fmt.Fprintf(os.Stderr, "DEBUG: vc is %s\n", vc)
 	} else {
-		fmt.Printf("Unable to find env var GOVMOMI_URL, has it been set?\n", vc)
+		fmt.Fprintf(os.Stderr, "Unable to find env var GOVMOMI_URL, has it been set?\n")
 		return
 	}
 
 	user := os.Getenv("GOVMOMI_USERNAME")
 
 	if len(user) > 0 {
-		fmt.Printf("DEBUG: user is %s\n", user)
+		fmt.Fprintf(os.Stderr, "DEBUG: user is %s\n", user)
 	} else {
-		fmt.Printf("Unable to find env var GOVMOMI_USERNAME, has it been set?\n", vc)
+		fmt.Fprintf(os.Stderr, "Unable to find env var GOVMOMI_USERNAME, has it been set?\n")
 		return
 	}
 	pwd := os.Getenv("GOVMOMI_PASSWORD")
 
-	if len(pwd) > 0 {
-		fmt.Printf("DEBUG: password is %s\n", pwd)
-	} else {
-		fmt.Printf("Unable to find env GOVMOMI_PASSWORD, has it been set?\n", vc)
+	if len(pwd) == 0 {
+		fmt.Fprintf(os.Stderr, "Unable to find env GOVMOMI_PASSWORD, has it been set?\n")
 		return
 	}
 
-	//
-	// This section allows for insecure vSphere logins
-	//
-
-	var insecure bool
-	flag.BoolVar(&insecure, "insecure", true, "ignore any vCenter TLS cert validation error")
-
 	//
 	// Imagine that there were multiple operations taking place such as processing some data, logging into vCenter, etc.
-	// If one of the operations failed, the context would be used to share the fact that all of the other operations 
+	// If one of the operations failed, the context would be used to share the fact that all of the other operations
 	// sharing that context needs cancelling.
 	//
 
@@ -90,11 +1450,11 @@ func main() {
 	u, err := soap.ParseURL(vc)
 
 	if u == nil {
-		fmt.Println("could not parse URL (environment variables set?)")
+		fmt.Fprintln(os.Stderr, "could not parse URL (environment variables set?)")
 	}
 
 	if err != nil {
-		fmt.Println("URL parsing not successful, error ", err)
+		fmt.Fprintln(os.Stderr, "URL parsing not successful, error ", err)
 		return
 	}
 
@@ -103,11 +1463,12 @@ func main() {
 	//
 	// Share session cache
 	//
-	        s := &cache.Session{
-	                URL:      u,
-	                Insecure: true,
-	        }
-
+	// This section allows for insecure vSphere logins
+	//
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
 
 	//-------------------------------------------------------------------
 	//
@@ -128,108 +1489,59 @@ func main() {
 	//  If something goes wrong the function should create a new error object with the appropriate messaging.
 	//
 
-	c := new (vim25.Client)
+	c := new(vim25.Client)
 
 	err = s.Login(ctx, c, nil)
 
 	if err != nil {
-		fmt.Println("")
-		fmt.Println("Log in not successful (govmomi) - could not get vCenter client: ", err)
-		fmt.Println("")
+		fmt.Fprintln(os.Stderr, "Log in not successful (govmomi) - could not get vCenter client: ", err)
 		return
-	} else {
-		fmt.Println("")
-		fmt.Println("Log in successful (govmomi)")
-		fmt.Println("")
 	}
 
-	// Create a view of DVS Network objects
+	fmt.Fprintln(os.Stderr, "Log in successful (govmomi)")
 
-	m := view.NewManager(c)
+	//
+	// -import reads a document and shows what would change, -apply then makes those changes
+	//
 
-	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualSwitch"}, true)
-	if err != nil {
-		fmt.Println("Error : could not create DVS container view: ", err)
+	if importFile != "" {
+		err = importDocument(ctx, c, importFile, datacenter, switchName, apply)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error : ", err)
+		}
+		return
 	}
 
-	defer v.Destroy(ctx)
-
-	// Retrieve summary property for all DVS
-  
-	var vds []mo.DistributedVirtualSwitch
-	err = v.Retrieve(ctx, []string{"DistributedVirtualSwitch"}, nil, &vds)
+	report, err := collect(ctx, c)
 	if err != nil {
-		fmt.Println("Error : could not retrieve DVS info: ", err)
-	}
-
-	fmt.Printf("\n")
-
-	// Print details per DVS
-	// Use 'govc object.collect network/DVS-Name' to see available fields to retrieve
-
-	for _, s := range vds {
-
-		// gomvomi interface provides access to the underlying base type (VMwareDVSConfigInfo)
-    
-		config := s.Config.(*types.VMwareDVSConfigInfo)
-
-		fmt.Printf("DVS Name is %s\n", config.Name)
-		fmt.Printf("DVS Config Status is %s\n", s.ConfigStatus)
-		fmt.Printf("DVS Overall Status is %s\n", s.OverallStatus)
-		fmt.Printf("DVS Config Version is %s\n", config.ConfigVersion)
-		fmt.Printf("DVS IP Address is %s\n", config.SwitchIpAddress)
-
-		// gomvomi interface provides access to the underlying base type (VMwareDVSPortSetting)
-    
-		portConfig := config.DefaultPortConfig.(*types.VMwareDVSPortSetting)
-	
-		// gomvomi interface provides access to the underlying base type (VmwareDistributedVirtualSwitchVlanIdSpec)
-    
-		vlan := portConfig.Vlan.(*types.VmwareDistributedVirtualSwitchVlanIdSpec)
-
-		// Display distributed switch vlan id,  if any
-		fmt.Printf("vlan id type = %T\n", vlan.VlanId)
-		fmt.Printf("vlan id = %v\n", vlan.VlanId)
-		fmt.Printf("policy inheritable = %t\n", vlan.InheritablePolicy.Inherited)
-
-		fmt.Printf("\n")
+		fmt.Fprintln(os.Stderr, "Error : ", err)
+		return
 	}
 
-	//
-	// Turning our attention to the distributed port groups, create a view of DVS PG Network objects
-	//
-  
-	v1, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualPortgroup"}, true)
+	err = filter.resolve(ctx, c, u.User)
 	if err != nil {
-		fmt.Println("Error : could not create DVS PG container view: ", err)
+		fmt.Fprintln(os.Stderr, "Error : could not apply tag filter: ", err)
+		return
 	}
 
-	defer v1.Destroy(ctx)
+	report = filterReport(report, &filter)
 
-	// Retrieve summary property for all DVS
-  
-	var vdspg []mo.DistributedVirtualPortgroup
-	err = v1.Retrieve(ctx, []string{"DistributedVirtualPortgroup"}, nil, &vdspg)
-	if err != nil {
-		fmt.Println("Error : could not retrieve DVS PG info: ", err)
+	if exportFile != "" {
+		err = writeDocument(exportFile, exportDocument(report, switchName))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error : could not export DVS configuration: ", err)
+		}
+		return
 	}
 
-	fmt.Printf("\n")
-
-	// Print details per DVS-PG
-	// Use 'govc object.collect /DC/network/DVPG-Name' to see available fields to retrieve
-
-	for _, pg := range vdspg {
-		fmt.Printf("Name of PG is %s\n", pg.Name)
-		dpgPortConfig := pg.Config.DefaultPortConfig.(*types.VMwareDVSPortSetting)
-
-	// Need the switch/case to avoid picking up uplinks which have trunk vlans and are a different type
-
-		switch dpgVlan := dpgPortConfig.Vlan.(type) {
-		case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
-			fmt.Printf("Vlan id=%d\n", dpgVlan.VlanId)
-		default:
-			fmt.Printf("\tIgnoring VLAN %s of type %T\n", pg.Name, dpgVlan)
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error : could not encode JSON: ", err)
 		}
+		return
 	}
+
+	printTable(report)
 }
@@ -0,0 +1,494 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and collect a troubleshooting bundle for one VM - typically a Kubernetes node that
+//			went NotReady - into a tarball for incident review:
+//
+//			  summary.txt       power, connection and overall status, guest heartbeat, VMware Tools
+//			                    status, guest hostname/IP, uptime and usage, plus the state of its host
+//			  screenshot.png    the VM console
+//			  vm-events.txt     the VM events of the last -since (default 24h)
+//			  host-events.txt   the host events over the same window
+//			  node.txt          the Kubernetes node conditions (with -node)
+//			  errors.txt        anything that could not be collected
+//
+//			The VM is picked with -vm (name or inventory path) or -node (a Kubernetes node in the current
+//			kubeconfig context, as listed by get-k8s-nodes). The bundle is written to -out, by default
+//			<vm>-<date>-<time>.tar.gz in the current directory.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vmware/govmomi/event"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+//
+// findVM resolves a VM by inventory path (starts with "/") or by name
+//
+
+func findVM(ctx context.Context, c *vim25.Client, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		vm, err := find.NewFinder(c).VirtualMachine(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		return vm.Reference(), nil
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{"VirtualMachine"}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no VM named %s", arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d VMs are named %s, use the path instead:\n  %s", len(refs), arg, strings.Join(paths, "\n  "))
+}
+
+//
+// nodeVM finds the VM behind the -node argument, and returns the node too so its conditions go in the bundle
+//
+
+func nodeVM(ctx context.Context, c *vim25.Client, kubeconfig, name string) (*corev1.Node, types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, none, err
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, none, err
+	}
+
+	node, err := clientSet.CoreV1().Nodes().Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return nil, none, fmt.Errorf("could not get node %s: %s", name, err)
+	}
+
+	if uuid := strings.TrimPrefix(node.Spec.ProviderID, "vsphere://"); uuid != node.Spec.ProviderID {
+		ref, err := object.NewSearchIndex(c).FindByUuid(ctx, nil, uuid, true, nil)
+		if err != nil {
+			return nil, none, err
+		}
+		if ref != nil {
+			return node, ref.Reference(), nil
+		}
+	}
+
+	vms, err := find.NewFinder(c).VirtualMachineList(ctx, "/*/vm/.../"+name)
+	if err != nil || len(vms) != 1 {
+		return nil, none, fmt.Errorf("could not find a VM for node %s", name)
+	}
+	return node, vms[0].Reference(), nil
+}
+
+//
+// Bundle holds the files of the tarball in the order they were added
+//
+
+type Bundle struct {
+	Names  []string
+	Files  map[string][]byte
+	Errors []string
+}
+
+func (b *Bundle) add(name string, data []byte) {
+	if b.Files == nil {
+		b.Files = make(map[string][]byte)
+	}
+	b.Names = append(b.Names, name)
+	b.Files[name] = data
+}
+
+func (b *Bundle) fail(what string, err error) {
+	fmt.Printf("! could not collect %s, error %v\n", what, err)
+	b.Errors = append(b.Errors, fmt.Sprintf("%s: %v", what, err))
+}
+
+// write saves the bundle as a gzip'd tarball, with every file under a directory named after the tarball
+func (b *Bundle) write(out string) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	dir := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(out), ".gz"), ".tar")
+	now := time.Now()
+
+	for _, name := range b.Names {
+		data := b.Files[name]
+		hdr := &tar.Header{
+			Name:    dir + "/" + name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out, buf.Bytes(), 0644)
+}
+
+//
+// summary describes the VM and its host as name/value lines
+//
+
+func summary(path string, vm mo.VirtualMachine, host *mo.HostSystem) []byte {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	line := func(name string, value interface{}) { fmt.Fprintf(w, "%s:\t%v\n", name, value) }
+
+	line("VM", vm.Name)
+	line("Path", path)
+	line("Collected", time.Now().Format(time.RFC3339))
+	line("Power state", vm.Runtime.PowerState)
+	line("Connection state", vm.Runtime.ConnectionState)
+	line("Overall status", vm.OverallStatus)
+	line("Guest heartbeat", vm.GuestHeartbeatStatus)
+	if vm.Runtime.BootTime != nil {
+		line("Boot time", vm.Runtime.BootTime.Format(time.RFC3339))
+	}
+	if vm.Runtime.Question != nil {
+		line("Pending question", vm.Runtime.Question.Text)
+	}
+
+	stats := vm.Summary.QuickStats
+	line("Uptime", time.Duration(stats.UptimeSeconds)*time.Second)
+	line("CPU usage", fmt.Sprintf("%d MHz", stats.OverallCpuUsage))
+	line("Guest memory usage", fmt.Sprintf("%d MB", stats.GuestMemoryUsage))
+
+	if g := vm.Guest; g != nil {
+		line("Tools status", g.ToolsStatus)
+		line("Tools running status", g.ToolsRunningStatus)
+		line("Tools version status", g.ToolsVersionStatus2)
+		line("Tools version", g.ToolsVersion)
+		line("Guest state", g.GuestState)
+		line("Guest OS", g.GuestFullName)
+		line("Guest hostname", g.HostName)
+		line("Guest IP", g.IpAddress)
+	}
+
+	if host != nil {
+		line("Host", host.Name)
+		line("Host connection state", host.Runtime.ConnectionState)
+		line("Host power state", host.Runtime.PowerState)
+		line("Host maintenance mode", host.Runtime.InMaintenanceMode)
+		line("Host overall status", host.OverallStatus)
+	}
+
+	w.Flush()
+	return buf.Bytes()
+}
+
+//
+// events lists the events logged against one object since the given time, oldest first
+//
+
+func events(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference, since time.Time) ([]byte, error) {
+	filter := types.EventFilterSpec{
+		Entity: &types.EventFilterSpecByEntity{
+			Entity:    ref,
+			Recursion: types.EventFilterSpecRecursionOptionSelf,
+		},
+		Time: &types.EventFilterSpecByTime{
+			BeginTime: types.NewTime(since),
+		},
+	}
+
+	list, err := event.NewManager(c).QueryEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Time\tType\tUser\tMessage\n")
+	for i := len(list) - 1; i >= 0; i-- {
+		e := list[i].GetEvent()
+		kind := reflect.TypeOf(list[i]).Elem().Name()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.CreatedTime.Format(time.RFC3339), kind, e.UserName, strings.TrimSpace(e.FullFormattedMessage))
+	}
+
+	w.Flush()
+	return buf.Bytes(), nil
+}
+
+//
+// screenshot downloads the console of the VM through the /screen URL of the vCenter or ESXi host
+//
+
+func screenshot(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference) ([]byte, error) {
+	u := c.URL()
+	u.Path = "/screen"
+	u.RawQuery = "id=" + ref.Value
+
+	r, _, err := c.Download(ctx, u, &soap.DefaultDownload)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// conditions lists the conditions and addresses of a Kubernetes node
+func conditions(node *corev1.Node) []byte {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Node:\t%s\n", node.Name)
+	fmt.Fprintf(w, "Provider ID:\t%s\n", node.Spec.ProviderID)
+	fmt.Fprintf(w, "Kubelet:\t%s\n", node.Status.NodeInfo.KubeletVersion)
+	for _, a := range node.Status.Addresses {
+		fmt.Fprintf(w, "%s:\t%s\n", a.Type, a.Address)
+	}
+
+	fmt.Fprintf(w, "\nCondition\tStatus\tLast heartbeat\tLast transition\tReason\tMessage\n")
+	for _, cond := range node.Status.Conditions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", cond.Type, cond.Status,
+			cond.LastHeartbeatTime.Format(time.RFC3339), cond.LastTransitionTime.Format(time.RFC3339), cond.Reason, cond.Message)
+	}
+
+	w.Flush()
+	return buf.Bytes()
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var name, nodeName, out string
+	var since time.Duration
+
+	var kubeconfig *string
+	if home := homedir.HomeDir(); home != "" {
+		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+	} else {
+		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	}
+
+	flag.StringVar(&name, "vm", "", "VM name or inventory path")
+	flag.StringVar(&nodeName, "node", "", "the VM behind this Kubernetes node")
+	flag.DurationVar(&since, "since", 24*time.Hour, "how far back to collect events")
+	flag.StringVar(&out, "out", "", "tarball to write (default <vm>-<date>-<time>.tar.gz)")
+	flag.Parse()
+
+	if (name == "") == (nodeName == "") {
+		fmt.Printf("give one of -vm or -node\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	var ref types.ManagedObjectReference
+	var node *corev1.Node
+
+	if nodeName != "" {
+		node, ref, err = nodeVM(ctx, c, *kubeconfig, nodeName)
+	} else {
+		ref, err = findVM(ctx, c, name)
+	}
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+
+	pc := property.DefaultCollector(c)
+
+	var vm mo.VirtualMachine
+	err = pc.RetrieveOne(ctx, ref, []string{"name", "runtime", "guest", "guestHeartbeatStatus", "overallStatus", "summary.quickStats"}, &vm)
+	if err != nil {
+		fmt.Printf("Could not retrieve VM %s, error %v\n", ref.Value, err)
+		return
+	}
+
+	path, _ := find.InventoryPath(ctx, c, ref)
+
+	if out == "" {
+		out = fmt.Sprintf("%s-%s.tar.gz", vm.Name, time.Now().Format("20060102-150405"))
+	}
+
+	//
+	// Collect what can be collected - a missing screenshot or event list is noted in errors.txt rather than
+	// stopping the bundle, since the VM or host being in a bad state is often why we are here
+	//
+
+	var b Bundle
+
+	var host *mo.HostSystem
+	if vm.Runtime.Host != nil {
+		host = new(mo.HostSystem)
+		err = pc.RetrieveOne(ctx, *vm.Runtime.Host, []string{"name", "runtime", "overallStatus"}, host)
+		if err != nil {
+			b.fail("host state", err)
+			host = nil
+		}
+	}
+
+	b.add("summary.txt", summary(path, vm, host))
+
+	if vm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn {
+		png, err := screenshot(ctx, c, ref)
+		if err != nil {
+			b.fail("console screenshot", err)
+		} else {
+			b.add("screenshot.png", png)
+		}
+	} else {
+		b.Errors = append(b.Errors, fmt.Sprintf("console screenshot: VM is %s", vm.Runtime.PowerState))
+	}
+
+	begin := time.Now().Add(-since)
+
+	if data, err := events(ctx, c, ref, begin); err != nil {
+		b.fail("VM events", err)
+	} else {
+		b.add("vm-events.txt", data)
+	}
+
+	if vm.Runtime.Host != nil {
+		if data, err := events(ctx, c, *vm.Runtime.Host, begin); err != nil {
+			b.fail("host events", err)
+		} else {
+			b.add("host-events.txt", data)
+		}
+	}
+
+	if node != nil {
+		b.add("node.txt", conditions(node))
+	}
+
+	if len(b.Errors) > 0 {
+		b.add("errors.txt", []byte(strings.Join(b.Errors, "\n")+"\n"))
+	}
+
+	fmt.Println()
+	fmt.Print(string(b.Files["summary.txt"]))
+	fmt.Println()
+
+	if err = b.write(out); err != nil {
+		fmt.Printf("Could not write %s, error %v\n", out, err)
+		return
+	}
+
+	fmt.Printf("Wrote %s (%s)\n", out, strings.Join(b.Names, ", "))
+}
@@ -0,0 +1,417 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and map every VM network adapter to the network it is attached to
+//
+//			For each virtual NIC the adapter type, MAC address and connected state are shown,
+//			along with the standard port group, distributed port group/port key or opaque (NSX)
+//			network backing it. VLAN IDs are resolved from the host port group configuration for
+//			standard port groups, and from the DVS port / port group configuration for distributed
+//			port groups (the same VLAN spec types decoded in get-vds-info.go).
+//
+//			Output is a table by default, or JSON with -json
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// NicInfo describes one virtual network adapter and what it is attached to
+type NicInfo struct {
+	VM          string `json:"vm"`
+	Label       string `json:"label"`
+	Type        string `json:"type"`
+	MacAddress  string `json:"macAddress"`
+	Connected   bool   `json:"connected"`
+	NetworkType string `json:"networkType"`
+	Network     string `json:"network"`
+	Switch      string `json:"switch,omitempty"`
+	PortKey     string `json:"portKey,omitempty"`
+	Vlan        string `json:"vlan"`
+}
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	//
+	// Create a vSphere/vCenter client
+	//
+	//    The govmomi client requires a URL object, u, not just a string representation of the vCenter URL.
+	//
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Fprintf(os.Stderr, "could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Log in successful\n")
+
+	return c, nil
+}
+
+//
+// vlanString renders the VLAN spec of a DVS port setting - a single VLAN ID, trunk ranges or a private VLAN ID
+//
+
+func vlanString(setting types.BaseDVPortSetting) string {
+	ps, ok := setting.(*types.VMwareDVSPortSetting)
+	if !ok || ps == nil {
+		return ""
+	}
+
+	switch vlan := ps.Vlan.(type) {
+	case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
+		return fmt.Sprintf("%d", vlan.VlanId)
+	case *types.VmwareDistributedVirtualSwitchTrunkVlanSpec:
+		var ranges []string
+		for _, r := range vlan.VlanId {
+			if r.Start == r.End {
+				ranges = append(ranges, fmt.Sprintf("%d", r.Start))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", r.Start, r.End))
+			}
+		}
+		return "trunk " + strings.Join(ranges, ",")
+	case *types.VmwareDistributedVirtualSwitchPvlanSpec:
+		return fmt.Sprintf("pvlan %d", vlan.PvlanId)
+	}
+	return ""
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var jsonOutput bool
+	flag.BoolVar(&jsonOutput, "json", false, "write the report as JSON instead of a table")
+	flag.Parse()
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//
+	// Call the login function
+	//
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := view.NewManager(c)
+
+	//
+	// Retrieve the virtual hardware of every VM - the NICs are found in config.hardware.device
+	//
+
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Virtual Machine Container View: error %s\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	var vms []mo.VirtualMachine
+	err = v.Retrieve(ctx, []string{"VirtualMachine"}, []string{"name", "config.hardware.device", "runtime.host"}, &vms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve VM information: error %s\n", err)
+		return
+	}
+
+	//
+	// Standard port group VLANs live on each host, so keep a host -> port group name -> host port group lookup
+	//
+
+	h, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"HostSystem"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Host Container View: error %s\n", err)
+		return
+	}
+
+	defer h.Destroy(ctx)
+
+	var hss []mo.HostSystem
+	err = h.Retrieve(ctx, []string{"HostSystem"}, []string{"name", "config.network.portgroup"}, &hss)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve Host information: error %s\n", err)
+		return
+	}
+
+	hostPortgroups := make(map[types.ManagedObjectReference]map[string]types.HostPortGroup)
+	for _, hs := range hss {
+		pgs := make(map[string]types.HostPortGroup)
+		if hs.Config != nil && hs.Config.Network != nil {
+			for _, pg := range hs.Config.Network.Portgroup {
+				pgs[pg.Spec.Name] = pg
+			}
+		}
+		hostPortgroups[hs.Reference()] = pgs
+	}
+
+	//
+	// Standard networks are referenced by MoRef, distributed port groups by switch UUID and port group key
+	//
+
+	n, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"Network"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Network Container View: error %s\n", err)
+		return
+	}
+
+	defer n.Destroy(ctx)
+
+	var nws []mo.Network
+	err = n.Retrieve(ctx, []string{"Network"}, []string{"name"}, &nws)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve Network information: error %s\n", err)
+		return
+	}
+
+	networkNames := make(map[types.ManagedObjectReference]string)
+	for _, nw := range nws {
+		networkNames[nw.Reference()] = nw.Name
+	}
+
+	d, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualSwitch"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create DVS Container View: error %s\n", err)
+		return
+	}
+
+	defer d.Destroy(ctx)
+
+	var vds []mo.DistributedVirtualSwitch
+	err = d.Retrieve(ctx, []string{"DistributedVirtualSwitch"}, []string{"name", "uuid"}, &vds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve DVS information: error %s\n", err)
+		return
+	}
+
+	switches := make(map[string]mo.DistributedVirtualSwitch)
+	for _, sw := range vds {
+		switches[sw.Uuid] = sw
+	}
+
+	p, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"DistributedVirtualPortgroup"}, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create DVS PG Container View: error %s\n", err)
+		return
+	}
+
+	defer p.Destroy(ctx)
+
+	var vdspg []mo.DistributedVirtualPortgroup
+	err = p.Retrieve(ctx, []string{"DistributedVirtualPortgroup"}, []string{"name", "key", "config.defaultPortConfig"}, &vdspg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to retrieve DVS PG information: error %s\n", err)
+		return
+	}
+
+	portgroups := make(map[string]mo.DistributedVirtualPortgroup)
+	for _, pg := range vdspg {
+		portgroups[pg.Key] = pg
+	}
+
+	//
+	// First pass: work out which DVS ports are in use, so that their VLAN can be fetched in one call per switch.
+	// A port can override the VLAN of its port group, so the port setting wins when it is available.
+	//
+
+	portKeys := make(map[string][]string)
+
+	for _, vm := range vms {
+		if vm.Config == nil {
+			continue
+		}
+		for _, dev := range object.VirtualDeviceList(vm.Config.Hardware.Device).SelectByType((*types.VirtualEthernetCard)(nil)) {
+			if b, ok := dev.GetVirtualDevice().Backing.(*types.VirtualEthernetCardDistributedVirtualPortBackingInfo); ok && b.Port.PortKey != "" {
+				portKeys[b.Port.SwitchUuid] = append(portKeys[b.Port.SwitchUuid], b.Port.PortKey)
+			}
+		}
+	}
+
+	portVlans := make(map[string]string)
+
+	for uuid, keys := range portKeys {
+		sw, ok := switches[uuid]
+		if !ok {
+			continue
+		}
+		dvs := object.NewDistributedVirtualSwitch(c, sw.Reference())
+		ports, err := dvs.FetchDVPorts(ctx, &types.DistributedVirtualSwitchPortCriteria{PortKey: keys})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to fetch ports of DVS %s: error %s\n", sw.Name, err)
+			continue
+		}
+		for _, port := range ports {
+			portVlans[uuid+"/"+port.Key] = vlanString(port.Config.Setting)
+		}
+	}
+
+	//
+	// Second pass: build a row per NIC
+	//
+
+	var nics []NicInfo
+
+	for _, vm := range vms {
+		if vm.Config == nil {
+			continue
+		}
+
+		devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
+
+		for _, dev := range devices.SelectByType((*types.VirtualEthernetCard)(nil)) {
+			card := dev.(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+
+			nic := NicInfo{
+				VM:         vm.Name,
+				Label:      devices.Name(dev),
+				Type:       strings.ToLower(strings.TrimPrefix(devices.TypeName(dev), "Virtual")),
+				MacAddress: card.MacAddress,
+			}
+
+			if card.DeviceInfo != nil {
+				nic.Label = card.DeviceInfo.GetDescription().Label
+			}
+			if card.Connectable != nil {
+				nic.Connected = card.Connectable.Connected
+			}
+
+			switch b := card.Backing.(type) {
+			case *types.VirtualEthernetCardNetworkBackingInfo:
+				nic.NetworkType = "standard"
+				nic.Network = b.DeviceName
+				if b.Network != nil && networkNames[*b.Network] != "" {
+					nic.Network = networkNames[*b.Network]
+				}
+				if vm.Runtime.Host != nil {
+					if pg, ok := hostPortgroups[*vm.Runtime.Host][nic.Network]; ok {
+						nic.Switch = pg.Spec.VswitchName
+						nic.Vlan = fmt.Sprintf("%d", pg.Spec.VlanId)
+					}
+				}
+			case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+				nic.NetworkType = "distributed"
+				nic.PortKey = b.Port.PortKey
+				nic.Switch = switches[b.Port.SwitchUuid].Name
+				if pg, ok := portgroups[b.Port.PortgroupKey]; ok {
+					nic.Network = pg.Name
+					nic.Vlan = vlanString(pg.Config.DefaultPortConfig)
+				} else {
+					nic.Network = b.Port.PortgroupKey
+				}
+				if vlan := portVlans[b.Port.SwitchUuid+"/"+b.Port.PortKey]; vlan != "" {
+					nic.Vlan = vlan
+				}
+			case *types.VirtualEthernetCardOpaqueNetworkBackingInfo:
+				nic.NetworkType = "opaque"
+				nic.Network = b.OpaqueNetworkId
+				nic.Switch = b.OpaqueNetworkType
+			default:
+				nic.NetworkType = fmt.Sprintf("%T", b)
+			}
+
+			nics = append(nics, nic)
+		}
+	}
+
+	sort.Slice(nics, func(i, j int) bool {
+		if nics[i].VM != nics[j].VM {
+			return nics[i].VM < nics[j].VM
+		}
+		return nics[i].Label < nics[j].Label
+	})
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(nics); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to encode JSON: error %s\n", err)
+		}
+		return
+	}
+
+	//
+	// Print a row per NIC
+	//
+	// -- https://golang.org/pkg/text/tabwriter/#NewWriter
+	//
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 4, ' ', 0)
+	fmt.Printf("\n*** VM Network Adapter Information ***\n")
+	fmt.Printf("---------------------------------------\n\n")
+	fmt.Fprintf(tw, "VM\tAdapter\tType\tMAC Address\tConnected\tBacking\tNetwork\tSwitch\tPort Key\tVLAN\n")
+	fmt.Fprintf(tw, "--\t-------\t----\t--- -------\t---------\t-------\t-------\t------\t---- ---\t----\n")
+
+	for _, nic := range nics {
+		fmt.Fprintf(tw, "%s\t", nic.VM)
+		fmt.Fprintf(tw, "%s\t", nic.Label)
+		fmt.Fprintf(tw, "%s\t", nic.Type)
+		fmt.Fprintf(tw, "%s\t", nic.MacAddress)
+		fmt.Fprintf(tw, "%t\t", nic.Connected)
+		fmt.Fprintf(tw, "%s\t", nic.NetworkType)
+		fmt.Fprintf(tw, "%s\t", nic.Network)
+		fmt.Fprintf(tw, "%s\t", nic.Switch)
+		fmt.Fprintf(tw, "%s\t", nic.PortKey)
+		fmt.Fprintf(tw, "%s\n", nic.Vlan)
+	}
+
+	fmt.Fprintf(tw, "\n")
+
+	_ = tw.Flush()
+}
@@ -5,6 +5,12 @@
 //
 // 			Login moved to function in this example
 //
+//			-info name|path|uuid shows a single VM in detail - disks, NICs, controllers, PCI passthrough,
+//			guest info, snapshot tree, resource allocation and host/cluster/resource pool placement
+//
+//			The list can be narrowed with -name (glob), -regex, -power, -guest, -host, -cluster, -folder,
+//			-datastore and -where 'cpu>=4' / 'mem>8GB' / 'storage>100GB', and ordered with -sort and -reverse
+//
 // Author:		Cormac J. Hogan (VMware)
 //
 // Date:		 25 Jan 2021
@@ -15,16 +21,29 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	pathpkg "path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
@@ -72,6 +91,735 @@ func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
 	}
 }
 
+//
+// tagFilter holds the -tag category:name values. With -tag-match all (the default) an object must carry every
+// tag, with -tag-match any it needs at least one of them.
+//
+
+type tagFilter struct {
+	tags    []string
+	match   string
+	objects map[types.ManagedObjectReference]bool
+}
+
+func (f *tagFilter) String() string { return strings.Join(f.tags, ",") }
+
+func (f *tagFilter) Set(value string) error {
+	if i := strings.Index(value, ":"); i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("want category:name, got %q", value)
+	}
+	f.tags = append(f.tags, value)
+	return nil
+}
+
+//
+// resolve finds the objects that pass the filter. Tags live behind the vAPI (REST) endpoint, which needs its own
+// login, and GetAttachedObjectsOnTags returns the objects for all of the tags in one call.
+//
+
+func (f *tagFilter) resolve(ctx context.Context, c *vim25.Client, user *url.Userinfo) error {
+	if len(f.tags) == 0 {
+		return nil
+	}
+	if f.match != "all" && f.match != "any" {
+		return fmt.Errorf("-tag-match must be \"all\" or \"any\", not %q", f.match)
+	}
+
+	rc := rest.NewClient(c)
+	if err := rc.Login(ctx, user); err != nil {
+		return fmt.Errorf("could not log in to the tagging service: %s", err)
+	}
+	defer rc.Logout(ctx)
+
+	m := tags.NewManager(rc)
+
+	var ids []string
+	for _, name := range f.tags {
+		i := strings.Index(name, ":")
+		tag, err := m.GetTagForCategory(ctx, name[i+1:], name[:i])
+		if err != nil {
+			return fmt.Errorf("could not find tag %s: %s", name, err)
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	attached, err := m.GetAttachedObjectsOnTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("could not get the objects tagged %s: %s", f, err)
+	}
+
+	count := make(map[types.ManagedObjectReference]int)
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			count[obj.Reference()]++
+		}
+	}
+
+	f.objects = make(map[types.ManagedObjectReference]bool)
+	for ref, n := range count {
+		if f.match == "any" || n == len(ids) {
+			f.objects[ref] = true
+		}
+	}
+
+	return nil
+}
+
+// keep reports whether an object passes the filter - everything passes when no -tag was given
+func (f *tagFilter) keep(ref types.ManagedObjectReference) bool {
+	return len(f.tags) == 0 || f.objects[ref]
+}
+
+//
+// customFields returns the custom attribute definitions that apply to a managed object type - the ones defined
+// for that type plus the global ones - sorted by name. Only vCenter has a custom fields manager.
+//
+
+func customFields(ctx context.Context, c *vim25.Client, moType string) ([]types.CustomFieldDef, error) {
+	m, err := object.GetCustomFieldsManager(c)
+	if err == object.ErrNotSupported {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := m.Field(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []types.CustomFieldDef
+	for _, def := range all {
+		if def.ManagedObjectType == "" || def.ManagedObjectType == moType {
+			fields = append(fields, def)
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	return fields, nil
+}
+
+// customValue returns the value an object holds for a custom attribute, or "-" when it is not set
+func customValue(values []types.BaseCustomFieldValue, key int32) string {
+	value := ""
+	for _, v := range values {
+		if sv, ok := v.(*types.CustomFieldStringValue); ok && sv.Key == key {
+			value = sv.Value
+		}
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// uuidPattern matches a BIOS or instance UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+//
+// findVM resolves a VM given as an inventory path (starts with "/"), a BIOS or instance UUID, or a name.
+// A name that matches more than one VM is an error listing their paths.
+//
+
+func findVM(ctx context.Context, c *vim25.Client, arg string) (types.ManagedObjectReference, error) {
+	if !uuidPattern.MatchString(arg) {
+		return findObject(ctx, c, "VirtualMachine", arg)
+	}
+
+	si := object.NewSearchIndex(c)
+	for _, instanceUUID := range []bool{false, true} {
+		ref, err := si.FindByUuid(ctx, nil, arg, true, &instanceUUID)
+		if err != nil {
+			return types.ManagedObjectReference{}, err
+		}
+		if ref != nil {
+			return ref.Reference(), nil
+		}
+	}
+
+	return types.ManagedObjectReference{}, fmt.Errorf("no VM with UUID %s", arg)
+}
+
+// deviceType returns the device model, e.g. "Vmxnet3" or "ParaVirtualSCSIController"
+func deviceType(device types.BaseVirtualDevice) string {
+	return strings.TrimPrefix(strings.TrimPrefix(fmt.Sprintf("%T", device), "*types."), "Virtual")
+}
+
+// label returns the device label, e.g. "Hard disk 1", falling back to the device key when vCenter sent no DeviceInfo
+func label(d types.BaseVirtualDevice) string {
+	if info := d.GetVirtualDevice().DeviceInfo; info != nil {
+		return info.GetDescription().Label
+	}
+	return fmt.Sprintf("device %d", d.GetVirtualDevice().Key)
+}
+
+// allocation renders a CPU or memory allocation as reservation/limit/shares
+func allocation(a *types.ResourceAllocationInfo, unit string) string {
+	if a == nil {
+		return "-"
+	}
+
+	limit := "unlimited"
+	if a.Limit != nil && *a.Limit >= 0 {
+		limit = fmt.Sprintf("%d %s", *a.Limit, unit)
+	}
+
+	var reservation int64
+	if a.Reservation != nil {
+		reservation = *a.Reservation
+	}
+
+	shares := "-"
+	if a.Shares != nil {
+		shares = fmt.Sprintf("%s (%d)", a.Shares.Level, a.Shares.Shares)
+	}
+
+	return fmt.Sprintf("reservation %d %s, limit %s, shares %s", reservation, unit, limit, shares)
+}
+
+//
+// printSnapshots walks the snapshot tree depth first, indenting each child under its parent
+//
+
+func printSnapshots(trees []types.VirtualMachineSnapshotTree, current *types.ManagedObjectReference, depth int) {
+	for _, snap := range trees {
+		marker := ""
+		if current != nil && snap.Snapshot == *current {
+			marker = "  <- current"
+		}
+		fmt.Printf("  %s%s  (%s, %s)%s\n", strings.Repeat("  ", depth), snap.Name, snap.CreateTime.Format("2006-01-02 15:04:05"), snap.State, marker)
+		printSnapshots(snap.ChildSnapshotList, current, depth+1)
+	}
+}
+
+//
+// printVMInfo prints everything about one VM - identity, placement, resource allocation, guest, devices and snapshots
+//
+
+func printVMInfo(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference) error {
+	pc := property.DefaultCollector(c)
+
+	var vm mo.VirtualMachine
+	err := pc.RetrieveOne(ctx, ref, []string{"name", "config", "guest", "runtime", "snapshot", "resourcePool", "datastore", "network"}, &vm)
+	if err != nil {
+		return err
+	}
+
+	if vm.Config == nil {
+		return fmt.Errorf("%s has no configuration (inaccessible or orphaned?)", vm.Name)
+	}
+
+	//
+	// Names of the host, cluster, resource pool(s), datastores and networks referenced by the VM, in one call
+	//
+
+	names := make(map[types.ManagedObjectReference]string)
+	var refs []types.ManagedObjectReference
+
+	refs = append(refs, vm.Datastore...)
+	refs = append(refs, vm.Network...)
+
+	var host mo.HostSystem
+	if vm.Runtime.Host != nil {
+		if err = pc.RetrieveOne(ctx, *vm.Runtime.Host, []string{"name", "parent"}, &host); err == nil {
+			refs = append(refs, host.Self)
+			if host.Parent != nil {
+				refs = append(refs, *host.Parent)
+			}
+		}
+	}
+
+	// Resource pools nest, so walk up from the VM's pool to the cluster's root pool
+
+	var pools []string
+	for rp := vm.ResourcePool; rp != nil; {
+		var pool mo.ResourcePool
+		if err = pc.RetrieveOne(ctx, *rp, []string{"name", "parent"}, &pool); err != nil {
+			break
+		}
+		pools = append([]string{pool.Name}, pools...)
+		if pool.Parent == nil || pool.Parent.Type != "ResourcePool" {
+			break
+		}
+		rp = pool.Parent
+	}
+
+	if len(refs) > 0 {
+		var content []types.ObjectContent
+		if err = pc.Retrieve(ctx, refs, []string{"name"}, &content); err == nil {
+			for _, oc := range content {
+				for _, prop := range oc.PropSet {
+					if name, ok := prop.Val.(string); ok {
+						names[oc.Obj] = name
+					}
+				}
+			}
+		}
+	}
+
+	path, _ := find.InventoryPath(ctx, c, ref)
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+
+	fmt.Printf("\n*** VM %s ***\n\n", vm.Name)
+	fmt.Fprintf(tw, "Path:\t%s\n", path)
+	fmt.Fprintf(tw, "UUID:\t%s\n", vm.Config.Uuid)
+	fmt.Fprintf(tw, "Instance UUID:\t%s\n", vm.Config.InstanceUuid)
+	fmt.Fprintf(tw, "Guest OS:\t%s\n", vm.Config.GuestFullName)
+	fmt.Fprintf(tw, "HW Version:\t%s\n", vm.Config.Version)
+	fmt.Fprintf(tw, "Power State:\t%s\n", vm.Runtime.PowerState)
+	fmt.Fprintf(tw, "CPU:\t%d vCPU (%d cores per socket)\n", vm.Config.Hardware.NumCPU, vm.Config.Hardware.NumCoresPerSocket)
+	fmt.Fprintf(tw, "Memory:\t%d MB\n", vm.Config.Hardware.MemoryMB)
+	fmt.Fprintf(tw, "CPU Allocation:\t%s\n", allocation(vm.Config.CpuAllocation, "MHz"))
+	fmt.Fprintf(tw, "Memory Allocation:\t%s\n", allocation(vm.Config.MemoryAllocation, "MB"))
+	_ = tw.Flush()
+
+	fmt.Printf("\nPlacement\n---------\n")
+	cluster := "-"
+	if host.Parent != nil {
+		cluster = names[*host.Parent]
+		if host.Parent.Type != "ClusterComputeResource" {
+			cluster += " (standalone host)"
+		}
+	}
+	var datastores []string
+	for _, ds := range vm.Datastore {
+		datastores = append(datastores, names[ds])
+	}
+	fmt.Fprintf(tw, "Host:\t%s\n", host.Name)
+	fmt.Fprintf(tw, "Cluster:\t%s\n", cluster)
+	fmt.Fprintf(tw, "Resource Pool:\t%s\n", strings.Join(pools, " / "))
+	fmt.Fprintf(tw, "Datastores:\t%s\n", strings.Join(datastores, ", "))
+	_ = tw.Flush()
+
+	fmt.Printf("\nGuest\n-----\n")
+	if vm.Guest != nil {
+		fmt.Fprintf(tw, "Hostname:\t%s\n", vm.Guest.HostName)
+		fmt.Fprintf(tw, "Tools:\t%s, %s (version %s)\n", vm.Guest.ToolsRunningStatus, vm.Guest.ToolsVersionStatus2, vm.Guest.ToolsVersion)
+		fmt.Fprintf(tw, "IP Address:\t%s\n", vm.Guest.IpAddress)
+		for _, nic := range vm.Guest.Net {
+			fmt.Fprintf(tw, "  %s:\t%s %s\n", nic.MacAddress, nic.Network, strings.Join(nic.IpAddress, ", "))
+		}
+	}
+	_ = tw.Flush()
+
+	//
+	// -- https://pkg.go.dev/github.com/vmware/govmomi/object#VirtualDeviceList
+	//
+
+	devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
+
+	fmt.Printf("\nStorage Controllers\n-------------------\n")
+	fmt.Fprintf(tw, "Label\tType\tBus\tSharing\n")
+	for _, device := range devices {
+		var bus int32
+		sharing := "-"
+		switch ctl := device.(type) {
+		case types.BaseVirtualSCSIController:
+			bus = ctl.GetVirtualSCSIController().BusNumber
+			sharing = string(ctl.GetVirtualSCSIController().SharedBus)
+		case types.BaseVirtualSATAController, *types.VirtualNVMEController, *types.VirtualIDEController:
+			bus = ctl.(types.BaseVirtualController).GetVirtualController().BusNumber
+		default:
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", label(device), deviceType(device), bus, sharing)
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\nDisks\n-----\n")
+	fmt.Fprintf(tw, "Label\tSize\tController\tDatastore\tFile\tThin\n")
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+		disk := device.(*types.VirtualDisk)
+
+		controller := "-"
+		if ctl := devices.FindByKey(disk.ControllerKey); ctl != nil {
+			unit := int32(0)
+			if disk.UnitNumber != nil {
+				unit = *disk.UnitNumber
+			}
+			controller = fmt.Sprintf("%s:%d", devices.Name(ctl), unit)
+		}
+
+		file, datastore, thin := "-", "-", "-"
+		if backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			info := backing.GetVirtualDeviceFileBackingInfo()
+			file = info.FileName
+			if info.Datastore != nil {
+				datastore = names[*info.Datastore]
+			}
+		}
+		if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok && backing.ThinProvisioned != nil {
+			thin = fmt.Sprintf("%t", *backing.ThinProvisioned)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", label(disk), units.ByteSize(disk.CapacityInBytes), controller, datastore, file, thin)
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\nNetwork Adapters\n----------------\n")
+	fmt.Fprintf(tw, "Label\tType\tMAC\tConnected\tNetwork\n")
+	for _, device := range devices.SelectByType((*types.VirtualEthernetCard)(nil)) {
+		nic := device.(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+
+		network := "-"
+		switch backing := nic.Backing.(type) {
+		case *types.VirtualEthernetCardNetworkBackingInfo:
+			network = backing.DeviceName
+		case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+			network = names[types.ManagedObjectReference{Type: "DistributedVirtualPortgroup", Value: backing.Port.PortgroupKey}]
+			if network == "" {
+				network = backing.Port.PortgroupKey
+			}
+		case *types.VirtualEthernetCardOpaqueNetworkBackingInfo:
+			network = backing.OpaqueNetworkId
+		}
+
+		connected := false
+		if nic.Connectable != nil {
+			connected = nic.Connectable.Connected
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n", label(device), deviceType(device), nic.MacAddress, connected, network)
+	}
+	_ = tw.Flush()
+
+	passthrough := devices.SelectByType((*types.VirtualPCIPassthrough)(nil))
+	if len(passthrough) > 0 {
+		fmt.Printf("\nPCI Passthrough\n---------------\n")
+		for _, device := range passthrough {
+			pci := device.(*types.VirtualPCIPassthrough)
+			backing := "-"
+			switch b := pci.Backing.(type) {
+			case *types.VirtualPCIPassthroughDeviceBackingInfo:
+				backing = fmt.Sprintf("%s (id %s, vendor %x, device %s)", b.DeviceName, b.Id, b.VendorId, b.DeviceId)
+			case *types.VirtualPCIPassthroughVmiopBackingInfo:
+				backing = "vGPU profile " + b.Vgpu
+			case *types.VirtualPCIPassthroughDynamicBackingInfo:
+				backing = "dynamic DirectPath I/O"
+			}
+			fmt.Fprintf(tw, "%s:\t%s\n", label(pci), backing)
+		}
+		_ = tw.Flush()
+	}
+
+	fmt.Printf("\nSnapshots\n---------\n")
+	if vm.Snapshot == nil || len(vm.Snapshot.RootSnapshotList) == 0 {
+		fmt.Printf("  (none)\n")
+	} else {
+		printSnapshots(vm.Snapshot.RootSnapshotList, vm.Snapshot.CurrentSnapshot, 0)
+	}
+
+	fmt.Printf("\n")
+
+	return nil
+}
+
+//
+// findObject resolves an inventory object of the given type by inventory path (starts with "/") or by name
+//
+
+func findObject(ctx context.Context, c *vim25.Client, kind, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		elements, err := find.NewFinder(c).ManagedObjectList(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		for _, e := range elements {
+			if e.Object.Reference().Type == kind {
+				return e.Object.Reference(), nil
+			}
+		}
+		return none, fmt.Errorf("no %s at %s", kind, arg)
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{kind}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{kind}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no %s named %s", kind, arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d objects of type %s are named %s, use the path instead:\n  %s", len(refs), kind, arg, strings.Join(paths, "\n  "))
+}
+
+//
+// condition is one -where resource threshold, e.g. "cpu>8" or "mem>=32GB"
+//
+
+type condition struct {
+	field string
+	op    string
+	value float64
+}
+
+var conditionPattern = regexp.MustCompile(`^(cpu|cpursv|mem|memrsv|storage)\s*(>=|<=|!=|>|<|=)\s*([0-9.]+)\s*([a-zA-Z]*)$`)
+
+//
+// parseCondition reads a threshold. mem and memrsv are compared in MB and storage (committed) in bytes, so a
+// unit suffix (MB, GB, TB) is converted to those.
+//
+
+func parseCondition(expr string) (condition, error) {
+	var cond condition
+
+	m := conditionPattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return cond, fmt.Errorf("bad -where %q, want <cpu|cpursv|mem|memrsv|storage><op><number>[MB|GB|TB]", expr)
+	}
+
+	value, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return cond, fmt.Errorf("bad -where %q: %s", expr, err)
+	}
+
+	unit := map[string]float64{"": 1, "MB": 1, "GB": 1024, "TB": 1024 * 1024}
+	if m[1] == "storage" {
+		unit = map[string]float64{"": 1, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+	}
+
+	scale, ok := unit[strings.ToUpper(m[4])]
+	if !ok || (m[4] != "" && (m[1] == "cpu" || m[1] == "cpursv")) {
+		return cond, fmt.Errorf("bad -where %q: unit %q does not apply to %s", expr, m[4], m[1])
+	}
+
+	return condition{field: m[1], op: m[2], value: value * scale}, nil
+}
+
+func (cond condition) match(vm mo.VirtualMachine) bool {
+	var v float64
+
+	switch cond.field {
+	case "cpu":
+		v = float64(vm.Summary.Config.NumCpu)
+	case "cpursv":
+		v = float64(vm.Summary.Config.CpuReservation)
+	case "mem":
+		v = float64(vm.Summary.Config.MemorySizeMB)
+	case "memrsv":
+		v = float64(vm.Summary.Config.MemoryReservation)
+	case "storage":
+		if vm.Summary.Storage != nil {
+			v = float64(vm.Summary.Storage.Committed)
+		}
+	}
+
+	switch cond.op {
+	case ">":
+		return v > cond.value
+	case ">=":
+		return v >= cond.value
+	case "<":
+		return v < cond.value
+	case "<=":
+		return v <= cond.value
+	case "!=":
+		return v != cond.value
+	}
+	return v == cond.value
+}
+
+//
+// sortVMs orders the report by one of its columns - the numeric columns sort as numbers, the rest as text
+//
+
+var sortColumns = []string{"name", "guest", "cpu", "cpursv", "mem", "memrsv", "state", "hw", "ip", "path"}
+
+func sortVMs(vms []mo.VirtualMachine, column string, reverse bool) {
+	number := func(vm mo.VirtualMachine) int64 {
+		switch column {
+		case "cpu":
+			return int64(vm.Summary.Config.NumCpu)
+		case "cpursv":
+			return int64(vm.Summary.Config.CpuReservation)
+		case "mem":
+			return int64(vm.Summary.Config.MemorySizeMB)
+		case "memrsv":
+			return int64(vm.Summary.Config.MemoryReservation)
+		}
+		return 0
+	}
+
+	text := func(vm mo.VirtualMachine) string {
+		switch column {
+		case "guest":
+			return vm.Summary.Guest.GuestId
+		case "state":
+			return string(vm.Summary.Runtime.PowerState)
+		case "hw":
+			return vm.Summary.Guest.HwVersion
+		case "ip":
+			return vm.Summary.Guest.IpAddress
+		case "path":
+			return vm.Summary.Config.VmPathName
+		}
+		return vm.Summary.Config.Name
+	}
+
+	sort.SliceStable(vms, func(i, j int) bool {
+		a, b := vms[i], vms[j]
+		if reverse {
+			a, b = b, a
+		}
+		switch column {
+		case "cpu", "cpursv", "mem", "memrsv":
+			return number(a) < number(b)
+		}
+		return text(a) < text(b)
+	})
+}
+
+//
+// vmSearch is the set of report filters. Scoping to a host, cluster or folder is done by the container view
+// itself, and name and power state by the property collector filter, so only the VMs that pass those are
+// retrieved in full. The regex, guest OS and threshold checks need the summary and run client side.
+//
+
+type vmSearch struct {
+	name, regex, power, guest string
+	host, cluster, folder, ds string
+	where                     []condition
+	re                        *regexp.Regexp
+}
+
+func (s *vmSearch) find(ctx context.Context, c *vim25.Client) ([]types.ManagedObjectReference, error) {
+	var scopes []types.ManagedObjectReference
+
+	for _, scope := range []struct{ kind, arg string }{
+		{"HostSystem", s.host}, {"ClusterComputeResource", s.cluster}, {"Folder", s.folder},
+	} {
+		if scope.arg == "" {
+			continue
+		}
+		ref, err := findObject(ctx, c, scope.kind, scope.arg)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, ref)
+	}
+
+	if len(scopes) == 0 {
+		scopes = append(scopes, c.ServiceContent.RootFolder)
+	}
+
+	filter := property.Filter{}
+	if s.name != "" {
+		filter["name"] = s.name
+	}
+	if s.power != "" {
+		filter["runtime.powerState"] = s.power
+	}
+
+	//
+	// Each scope is its own container view - a VM has to be in all of them
+	//
+
+	m := view.NewManager(c)
+	var refs []types.ManagedObjectReference
+
+	for i, scope := range scopes {
+		v, err := m.CreateContainerView(ctx, scope, []string{"VirtualMachine"}, true)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := v.Find(ctx, []string{"VirtualMachine"}, filter)
+		_ = v.Destroy(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			refs = found
+			continue
+		}
+		refs = intersect(refs, found)
+	}
+
+	if s.ds != "" {
+		ref, err := findObject(ctx, c, "Datastore", s.ds)
+		if err != nil {
+			return nil, err
+		}
+		var ds mo.Datastore
+		if err = property.DefaultCollector(c).RetrieveOne(ctx, ref, []string{"vm"}, &ds); err != nil {
+			return nil, err
+		}
+		refs = intersect(refs, ds.Vm)
+	}
+
+	return refs, nil
+}
+
+func (s *vmSearch) keep(vm mo.VirtualMachine) bool {
+	if s.re != nil && !s.re.MatchString(vm.Summary.Config.Name) {
+		return false
+	}
+
+	if s.guest != "" {
+		pattern := strings.ToLower(s.guest)
+		id, _ := pathpkg.Match(pattern, strings.ToLower(vm.Summary.Config.GuestId))
+		full, _ := pathpkg.Match(pattern, strings.ToLower(vm.Summary.Config.GuestFullName))
+		if !id && !full {
+			return false
+		}
+	}
+
+	for _, cond := range s.where {
+		if !cond.match(vm) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func intersect(a, b []types.ManagedObjectReference) []types.ManagedObjectReference {
+	in := make(map[types.ManagedObjectReference]bool)
+	for _, ref := range b {
+		in[ref] = true
+	}
+
+	var both []types.ManagedObjectReference
+	for _, ref := range a {
+		if in[ref] {
+			both = append(both, ref)
+		}
+	}
+	return both
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
 
 	// We need to get 3 environment variables:
@@ -79,6 +827,66 @@ func main() {
 	//-- GOVMOMI_URL
 	//-- GOVMOMI_USERNAME
 	//-- GOVMOMI_PASSWORD
+	//
+	// -tag category:name (repeatable) scopes the report to tagged VMs
+	// -info name|path|uuid shows the detailed view of one VM instead of the report
+
+	var info string
+	flag.StringVar(&info, "info", "", "show the details of one VM, given by name, inventory path or UUID")
+
+	var filter tagFilter
+	flag.Var(&filter, "tag", "only report VMs with this category:name tag (repeat for more than one tag)")
+	flag.StringVar(&filter.match, "tag-match", "all", "with more than one -tag, VMs must have \"all\" of the tags or \"any\" of them")
+
+	//
+	// Search filters - all of them must match
+	//
+
+	var search vmSearch
+	var where stringList
+	var sortBy string
+	var reverse bool
+	flag.StringVar(&search.name, "name", "", "only VMs whose name matches this glob, e.g. web-*")
+	flag.StringVar(&search.regex, "regex", "", "only VMs whose name matches this regular expression")
+	flag.StringVar(&search.power, "power", "", "only VMs in this power state (poweredOn, poweredOff, suspended)")
+	flag.StringVar(&search.guest, "guest", "", "only VMs whose guest OS id or name matches this glob, e.g. '*ubuntu*'")
+	flag.StringVar(&search.host, "host", "", "only VMs on this host (name or inventory path)")
+	flag.StringVar(&search.cluster, "cluster", "", "only VMs in this cluster (name or inventory path)")
+	flag.StringVar(&search.folder, "folder", "", "only VMs under this folder (name or inventory path)")
+	flag.StringVar(&search.ds, "datastore", "", "only VMs with files on this datastore (name or inventory path)")
+	flag.Var(&where, "where", "resource threshold, e.g. cpu>8, mem>=32GB, storage>500GB (repeatable)")
+	flag.StringVar(&sortBy, "sort", "name", "sort by column: "+strings.Join(sortColumns, ", "))
+	flag.BoolVar(&reverse, "reverse", false, "reverse the sort order")
+	flag.Parse()
+
+	if search.regex != "" {
+		re, err := regexp.Compile(search.regex)
+		if err != nil {
+			fmt.Printf("Bad -regex: %s\n", err)
+			return
+		}
+		search.re = re
+	}
+
+	for _, expr := range where {
+		cond, err := parseCondition(expr)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		search.where = append(search.where, cond)
+	}
+
+	validSort := false
+	for _, column := range sortColumns {
+		if column == sortBy {
+			validSort = true
+		}
+	}
+	if !validSort {
+		fmt.Printf("-sort must be one of: %s\n", strings.Join(sortColumns, ", "))
+		return
+	}
 
 	vc := os.Getenv("GOVMOMI_URL")
 	user := os.Getenv("GOVMOMI_USERNAME")
@@ -90,7 +898,7 @@ func main() {
 
 	//
 	// Imagine that there were multiple operations taking place such as processing some data, logging into vCenter, etc.
-	// If one of the operations failed, the context would be used to share the fact that all of the other operations 
+	// If one of the operations failed, the context would be used to share the fact that all of the other operations
 	// sharing that context needs cancelling.
 	//
 
@@ -102,38 +910,62 @@ func main() {
 	//
 
 	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
 
-	//
-	// Create a view manager - a mechanism that supports selection of objects on the server and subsequently, access to those objects.
-	//
-	// Ref: https://vdc-download.vmware.com/vmwb-repository/dcr-public/b50dcbbf-051d-4204-a3e7-e1b618c1e384/538cf2ec-b34f-4bae-a332-3820ef9e7773/vim.view.ViewManager.html
-	//
+	if info != "" {
+		ref, err := findVM(ctx, c, info)
+		if err != nil {
+			fmt.Printf("\nUnable to find VM: %s\n", err)
+			return
+		}
+		if err = printVMInfo(ctx, c, ref); err != nil {
+			fmt.Printf("\nUnable to retrieve VM details: error %s\n", err)
+		}
+		return
+	}
 
-	m := view.NewManager(c)
+	err = filter.resolve(ctx, c, url.UserPassword(user, pwd))
+	if err != nil {
+		fmt.Printf("Unable to apply tag filter: error %s\n", err)
+		return
+	}
 
 	//
-	// Create a container view (a means of monitoring the contents of a single container) of VM objects
+	// Find the VMs through container views (a means of monitoring the contents of a single container) - see vmSearch
 	//
 	// Ref: https://vdc-download.vmware.com/vmwb-repository/dcr-public/b50dcbbf-051d-4204-a3e7-e1b618c1e384/538cf2ec-b34f-4bae-a332-3820ef9e7773/vim.view.ContainerView.html
 	//
 
-	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	refs, err := search.find(ctx, c)
 	if err != nil {
-		fmt.Printf("Unable to create Virtual Machine Container View: error %s", err)
+		fmt.Printf("Unable to find Virtual Machines: error %s\n", err)
 		return
 	}
 
-	defer v.Destroy(ctx)
-
 	//
-	// Retrieve summary property for all machines
+	// Retrieve summary and custom attribute values for the machines found
 	//
 
 	var vms []mo.VirtualMachine
-	err = v.Retrieve(ctx, []string{"VirtualMachine"}, []string{"summary"}, &vms)
+	if len(refs) > 0 {
+		err = property.DefaultCollector(c).Retrieve(ctx, refs, []string{"summary", "customValue"}, &vms)
+		if err != nil {
+			fmt.Printf("Unable to retrieve VM information: error %s", err)
+			return
+		}
+	}
 
+	sortVMs(vms, sortBy, reverse)
+
+	//
+	// Each custom attribute (custom field) defined for VMs becomes an extra column
+	//
+
+	fields, err := customFields(ctx, c, "VirtualMachine")
 	if err != nil {
-		fmt.Printf("Unable to retrieve VM information: error %s", err)
+		fmt.Printf("Unable to retrieve custom attribute definitions: error %s\n", err)
 		return
 	}
 
@@ -146,10 +978,21 @@ func main() {
 	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 4, ' ', 0)
 	fmt.Printf("\n*** VM Information ***\n")
 	fmt.Printf("-----------------------\n\n")
-	fmt.Fprintf(tw, "Name\tGuest\tCPU\tCPU Rsv\tMem(MB)\tMem Rsv\tState\tHW Version\tIP Address\tVM Path\n")
-	fmt.Fprintf(tw, "----\t-----\t---\t--- ---\t-------\t--- ---\t-----\t-- -------\t-- -------\t-- ----\n")
+	fmt.Fprintf(tw, "Name\tGuest\tCPU\tCPU Rsv\tMem(MB)\tMem Rsv\tState\tHW Version\tIP Address\tVM Path")
+	for _, field := range fields {
+		fmt.Fprintf(tw, "\t%s", field.Name)
+	}
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "----\t-----\t---\t--- ---\t-------\t--- ---\t-----\t-- -------\t-- -------\t-- ----")
+	for _, field := range fields {
+		fmt.Fprintf(tw, "\t%s", strings.Repeat("-", len(field.Name)))
+	}
+	fmt.Fprintf(tw, "\n")
 
 	for _, vm := range vms {
+		if !filter.keep(vm.Reference()) || !search.keep(vm) {
+			continue
+		}
 		fmt.Fprintf(tw, "%s:\t", vm.Summary.Config.Name)
 		fmt.Fprintf(tw, "%s\t", vm.Summary.Guest.GuestId)
 		fmt.Fprintf(tw, "%v\t", vm.Summary.Config.NumCpu)
@@ -159,7 +1002,11 @@ func main() {
 		fmt.Fprintf(tw, "%s\t", vm.Summary.Runtime.PowerState)
 		fmt.Fprintf(tw, "%s\t", vm.Summary.Guest.HwVersion)
 		fmt.Fprintf(tw, "%s\t", vm.Summary.Guest.IpAddress)
-		fmt.Fprintf(tw, "%s\t\n", vm.Summary.Config.VmPathName)
+		fmt.Fprintf(tw, "%s\t", vm.Summary.Config.VmPathName)
+		for _, field := range fields {
+			fmt.Fprintf(tw, "%s\t", customValue(vm.CustomValue, field.Key))
+		}
+		fmt.Fprintf(tw, "\n")
 	}
 
 	fmt.Fprintf(tw, "\n")
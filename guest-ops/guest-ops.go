@@ -0,0 +1,588 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and run a program or copy files inside the guest OS of one or more VMs,
+//			through VMware Tools
+//
+//			  -run "uptime; df -h | grep ' /$'"            run a command through /bin/sh -c (cmd.exe /c on
+//			                                               Windows), print its output and exit code
+//			  -upload ./collect.sh -dest /tmp/collect.sh   copy a local file into the guest (-force overwrites)
+//			  -download /var/log/syslog [-dest ./logs]     copy a guest file to <dest>/<vm>/<file name>
+//
+//			VMs are picked with -vm (name or inventory path) or -node (a Kubernetes node in the current
+//			kubeconfig context, as listed by get-k8s-nodes), both repeatable. The guest credentials are
+//			taken from the GOVMOMI_GUEST_USERNAME and GOVMOMI_GUEST_PASSWORD environment variables
+//			unless -guest-user / -guest-password are given.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/guest/toolbox"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//
+// findVM resolves a VM by inventory path (starts with "/") or by name
+//
+
+func findVM(ctx context.Context, c *vim25.Client, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		vm, err := find.NewFinder(c).VirtualMachine(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		return vm.Reference(), nil
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{"VirtualMachine"}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no VM named %s", arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d VMs are named %s, use the path instead:\n  %s", len(refs), arg, strings.Join(paths, "\n  "))
+}
+
+//
+// nodeVMs resolves the -node arguments to the VMs whose guests the command or copy runs in
+//
+
+func nodeVMs(ctx context.Context, c *vim25.Client, kubeconfig string, names []string) ([]types.ManagedObjectReference, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	finder := find.NewFinder(c)
+	index := object.NewSearchIndex(c)
+	var refs []types.ManagedObjectReference
+
+	for _, name := range names {
+		node, err := clientSet.CoreV1().Nodes().Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get node %s: %s", name, err)
+		}
+
+		if uuid := strings.TrimPrefix(node.Spec.ProviderID, "vsphere://"); uuid != node.Spec.ProviderID {
+			ref, err := index.FindByUuid(ctx, nil, uuid, true, nil)
+			if err != nil {
+				return nil, err
+			}
+			if ref != nil {
+				refs = append(refs, ref.Reference())
+				continue
+			}
+		}
+
+		vms, err := finder.VirtualMachineList(ctx, "/*/vm/.../"+name)
+		if err != nil || len(vms) != 1 {
+			return nil, fmt.Errorf("could not find a VM for node %s", name)
+		}
+		refs = append(refs, vms[0].Reference())
+	}
+
+	return refs, nil
+}
+
+//
+// Result is what one guest operation returned - the output and exit code of -run, or the local/guest path
+// of a copied file
+//
+
+type Result struct {
+	Stdout   bytes.Buffer
+	Stderr   bytes.Buffer
+	ExitCode int
+	Path     string
+	Size     int64
+}
+
+//
+// program builds the guest program spec for the -run string. The command is handed unchanged to '/bin/sh -c'
+// (or 'cmd.exe /c' on Windows), so quoting, pipes and redirects in it work as in a shell. Its stdout and
+// stderr go to the two guest temp files - those redirects sit outside the quoted command, so they do not
+// clash with the command's own.
+//
+
+func program(family, run, stdout, stderr string) *types.GuestProgramSpec {
+	if family == string(types.VirtualMachineGuestOsFamilyWindowsGuest) {
+		return &types.GuestProgramSpec{
+			ProgramPath: "c:\\Windows\\System32\\cmd.exe",
+			Arguments:   fmt.Sprintf("/c %s 1> %s 2> %s", run, stdout, stderr),
+		}
+	}
+
+	// Inside single quotes only a single quote is special - end the quote, add an escaped one, reopen it
+
+	quoted := "'" + strings.ReplaceAll(run, "'", `'\''`) + "'"
+
+	return &types.GuestProgramSpec{
+		ProgramPath: "/bin/sh",
+		Arguments:   fmt.Sprintf("-c %s 1> %s 2> %s", quoted, stdout, stderr),
+	}
+}
+
+//
+// runCommand runs the command in the guest, waiting up to timeout for it to exit. A command that is still
+// running at the timeout is killed. The temp files are removed with a fresh context, as ctx may have expired.
+//
+
+func runCommand(ctx context.Context, client *toolbox.Client, run string, timeout time.Duration, r *Result) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pm, fm, auth := client.ProcessManager, client.FileManager, client.Authentication
+
+	var files []string
+	defer func() {
+		cleanup, done := context.WithTimeout(context.Background(), 30*time.Second)
+		defer done()
+		for _, f := range files {
+			_ = fm.DeleteFile(cleanup, auth, f)
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		f, err := fm.CreateTemporaryFile(ctx, auth, "guest-ops-", "", "")
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	pid, err := pm.StartProgram(ctx, auth, program(string(client.GuestFamily), run, files[0], files[1]))
+	if err != nil {
+		return err
+	}
+
+	for {
+		procs, err := pm.ListProcesses(ctx, auth, []int64{pid})
+		if err == nil && len(procs) == 1 && procs[0].EndTime != nil {
+			r.ExitCode = int(procs[0].ExitCode)
+			break
+		}
+
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			kill, done := context.WithTimeout(context.Background(), 30*time.Second)
+			defer done()
+			if err := pm.TerminateProcess(kill, auth, pid); err != nil {
+				return fmt.Errorf("still running after %s, could not kill guest process %d: %s", timeout, pid, err)
+			}
+			return fmt.Errorf("still running after %s, guest process %d was killed", timeout, pid)
+		case <-time.After(time.Second / 2):
+		}
+	}
+
+	for i, w := range []io.Writer{&r.Stdout, &r.Stderr} {
+		rc, _, err := client.Download(ctx, files[i])
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// upload copies a local file to the guest, keeping its permissions on Linux guests
+func upload(ctx context.Context, client *toolbox.Client, src, dst string, force bool, r *Result) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var attr types.BaseGuestFileAttributes = &types.GuestWindowsFileAttributes{}
+	if client.GuestFamily != types.VirtualMachineGuestOsFamilyWindowsGuest {
+		attr = &types.GuestPosixFileAttributes{Permissions: int64(info.Mode().Perm())}
+	}
+
+	p := soap.DefaultUpload
+	p.ContentLength = info.Size()
+
+	if err = client.Upload(ctx, f, dst, p, attr, force); err != nil {
+		return err
+	}
+
+	r.Path = dst
+	r.Size = info.Size()
+	return nil
+}
+
+// download copies a guest file to <dir>/<vm>/<file name>
+func download(ctx context.Context, client *toolbox.Client, src, dir, vm string, r *Result) error {
+	rc, _, err := client.Download(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	defer rc.Close()
+
+	// Guest paths use the guest's separator - take the last element of either kind
+
+	base := path.Base(strings.ReplaceAll(src, "\\", "/"))
+	dst := filepath.Join(dir, vm, base)
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	r.Size, err = io.Copy(f, rc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	r.Path = dst
+	return nil
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+	//
+	// and 2 more for the guest OS, unless they are passed as flags:
+	//
+	//-- GOVMOMI_GUEST_USERNAME
+	//-- GOVMOMI_GUEST_PASSWORD
+
+	var names, nodes stringList
+	var run, up, down, dest, guestUser, guestPwd string
+	var force bool
+	var workers int
+	var timeout time.Duration
+
+	var kubeconfig *string
+	if home := homedir.HomeDir(); home != "" {
+		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+	} else {
+		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	}
+
+	flag.Var(&names, "vm", "VM name or inventory path (repeatable)")
+	flag.Var(&nodes, "node", "the VM behind this Kubernetes node (repeatable)")
+	flag.StringVar(&run, "run", "", "command to run in the guest")
+	flag.StringVar(&up, "upload", "", "local file to copy into the guest (needs -dest)")
+	flag.StringVar(&down, "download", "", "guest file to copy to <dest>/<vm>/")
+	flag.StringVar(&dest, "dest", "", "guest path for -upload, local directory for -download (default .)")
+	flag.BoolVar(&force, "force", false, "overwrite an existing guest file on -upload")
+	flag.StringVar(&guestUser, "guest-user", "", "guest OS user (default $GOVMOMI_GUEST_USERNAME)")
+	flag.StringVar(&guestPwd, "guest-password", "", "guest OS password (default $GOVMOMI_GUEST_PASSWORD)")
+	flag.IntVar(&workers, "workers", 4, "number of VMs to work on at once")
+	flag.DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for -run to exit")
+	flag.Parse()
+
+	// The guest credentials are read from the environment after parsing, so -h never prints them
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if !given["guest-user"] {
+		guestUser = os.Getenv("GOVMOMI_GUEST_USERNAME")
+	}
+	if !given["guest-password"] {
+		guestPwd = os.Getenv("GOVMOMI_GUEST_PASSWORD")
+	}
+
+	actions := 0
+	for _, a := range []string{run, up, down} {
+		if a != "" {
+			actions++
+		}
+	}
+
+	if actions != 1 {
+		fmt.Printf("give one of -run, -upload or -download\n")
+		return
+	}
+
+	if run != "" && strings.TrimSpace(run) == "" {
+		fmt.Printf("-run needs a command\n")
+		return
+	}
+
+	if up != "" && dest == "" {
+		fmt.Printf("-upload needs the guest path in -dest\n")
+		return
+	}
+
+	if down != "" && dest == "" {
+		dest = "."
+	}
+
+	if len(names) == 0 && len(nodes) == 0 {
+		fmt.Printf("select the VMs with -vm or -node\n")
+		return
+	}
+
+	if guestUser == "" {
+		fmt.Printf("set GOVMOMI_GUEST_USERNAME / GOVMOMI_GUEST_PASSWORD or use -guest-user / -guest-password\n")
+		return
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	var refs []types.ManagedObjectReference
+	for _, name := range names {
+		ref, err := findVM(ctx, c, name)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		refs = append(refs, ref)
+	}
+
+	if len(nodes) > 0 {
+		nodeRefs, err := nodeVMs(ctx, c, *kubeconfig, nodes)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		refs = append(refs, nodeRefs...)
+	}
+
+	// A VM given both by name and as a node is only worked on once
+
+	seen := make(map[types.ManagedObjectReference]bool)
+	unique := refs[:0]
+	for _, ref := range refs {
+		if !seen[ref] {
+			seen[ref] = true
+			unique = append(unique, ref)
+		}
+	}
+	refs = unique
+
+	var vms []mo.VirtualMachine
+	err = property.DefaultCollector(c).Retrieve(ctx, refs, []string{"name", "runtime.powerState", "guest.toolsRunningStatus"}, &vms)
+	if err != nil {
+		fmt.Printf("Could not retrieve VMs, error %v\n", err)
+		return
+	}
+
+	auth := &types.NamePasswordAuthentication{
+		Username: guestUser,
+		Password: guestPwd,
+	}
+
+	//
+	// Work on the VMs in parallel and print each VM's result in one piece as it completes
+	//
+
+	fmt.Println()
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+
+	for _, vm := range vms {
+		if vm.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+			fmt.Printf("! %s is %s\n", vm.Name, vm.Runtime.PowerState)
+			failed++
+			continue
+		}
+
+		if vm.Guest == nil || vm.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+			fmt.Printf("! %s VMware Tools are not running\n", vm.Name)
+			failed++
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(vm mo.VirtualMachine) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var r Result
+
+			client, err := toolbox.NewClient(ctx, c, vm.Self, auth)
+			if err == nil {
+				switch {
+				case run != "":
+					err = runCommand(ctx, client, run, timeout, &r)
+				case up != "":
+					err = upload(ctx, client, up, dest, force, &r)
+				default:
+					err = download(ctx, client, down, dest, vm.Name, &r)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				fmt.Printf("! %s failed, error %v\n", vm.Name, err)
+				failed++
+				return
+			}
+
+			switch {
+			case run != "":
+				fmt.Printf("=== %s (exit %d)\n", vm.Name, r.ExitCode)
+				fmt.Print(r.Stdout.String())
+				if r.Stderr.Len() > 0 {
+					fmt.Printf("--- stderr\n")
+					fmt.Print(r.Stderr.String())
+				}
+				fmt.Println()
+				if r.ExitCode != 0 {
+					failed++
+				}
+			case up != "":
+				fmt.Printf("+ %s:%s (%d bytes)\n", vm.Name, r.Path, r.Size)
+			default:
+				fmt.Printf("+ %s (%d bytes from %s)\n", r.Path, r.Size, vm.Name)
+			}
+		}(vm)
+	}
+
+	wg.Wait()
+
+	if failed > 0 {
+		fmt.Printf("%d of %d VMs failed\n", failed, len(vms))
+		os.Exit(1)
+	}
+}
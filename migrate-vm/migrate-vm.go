@@ -0,0 +1,875 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and migrate VMs - vMotion to another host or cluster, Storage vMotion to
+//			another datastore or datastore cluster, or both at once
+//
+//			  -vm web-01 -host esx-02                        compute migration (repeat -vm as needed)
+//			  -vm web-01 -datastore ds-02                    storage migration (-datastore-cluster for SDRS)
+//			  -evacuate-host esx-01                          move every VM off a host, spread over the
+//			                                                 other hosts of its cluster (or those of -cluster)
+//			  -evacuate-datastore ds-01                      move every VM off a datastore, to the datastores
+//			                                                 with the most free space
+//			  -plan-out plan.yaml / -plan plan.yaml          save a plan, and run a saved one later
+//
+//			Each move is checked with the VM provisioning checker first. Without -apply the plan and the
+//			check results are only printed; with -apply the moves run -workers at a time.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/progress"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/yaml"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//
+// findObject resolves an inventory object of the given type by inventory path (starts with "/") or by name
+//
+
+func findObject(ctx context.Context, c *vim25.Client, kind, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		elements, err := find.NewFinder(c).ManagedObjectList(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		for _, e := range elements {
+			if e.Object.Reference().Type == kind {
+				return e.Object.Reference(), nil
+			}
+		}
+		return none, fmt.Errorf("no %s at %s", kind, arg)
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{kind}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{kind}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no %s named %s", kind, arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d objects of type %s are named %s, use the path instead:\n  %s", len(refs), kind, arg, strings.Join(paths, "\n  "))
+}
+
+//
+// A Plan is a list of moves, by inventory path so it can be saved, read and edited. An empty Host, Pool or
+// Datastore means that part of the VM's placement stays as it is.
+//
+
+type Plan struct {
+	Moves []Move `json:"moves"`
+}
+
+type Move struct {
+	VM        string `json:"vm"`
+	Host      string `json:"host,omitempty"`
+	Pool      string `json:"pool,omitempty"`
+	Datastore string `json:"datastore,omitempty"`
+}
+
+func readPlan(file string) (Plan, error) {
+	var plan Plan
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return plan, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &plan)
+	return plan, err
+}
+
+func writePlan(file string, plan Plan) error {
+	data, err := yaml.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// pathOf returns the inventory path of an object, or "" for no object
+func pathOf(ctx context.Context, c *vim25.Client, ref *types.ManagedObjectReference) (string, error) {
+	if ref == nil {
+		return "", nil
+	}
+	return find.InventoryPath(ctx, c, *ref)
+}
+
+//
+// computeOf returns the cluster (or standalone host's compute resource) that a host or cluster belongs to, with
+// its root resource pool
+//
+
+func computeOf(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference) (*types.ManagedObjectReference, *types.ManagedObjectReference, error) {
+	pc := property.DefaultCollector(c)
+
+	if ref.Type == "HostSystem" {
+		var h mo.HostSystem
+		if err := pc.RetrieveOne(ctx, ref, []string{"parent"}, &h); err != nil {
+			return nil, nil, err
+		}
+		ref = *h.Parent
+	}
+
+	var cr mo.ComputeResource
+	if err := pc.RetrieveOne(ctx, ref, []string{"resourcePool"}, &cr); err != nil {
+		return nil, nil, err
+	}
+
+	return &ref, cr.ResourcePool, nil
+}
+
+//
+// podDatastore asks Storage DRS which datastore of a datastore cluster a VM should move to. Where Storage DRS
+// makes no recommendation, the datastore of the cluster with the most free space is used.
+//
+
+func podDatastore(ctx context.Context, c *vim25.Client, pod, vm types.ManagedObjectReference) (types.ManagedObjectReference, error) {
+	spec := types.StoragePlacementSpec{
+		Type:             string(types.StoragePlacementSpecPlacementTypeRelocate),
+		Vm:               &vm,
+		PodSelectionSpec: types.StorageDrsPodSelectionSpec{StoragePod: &pod},
+		RelocateSpec:     &types.VirtualMachineRelocateSpec{},
+	}
+
+	res, err := object.NewStorageResourceManager(c).RecommendDatastores(ctx, spec)
+	if err == nil {
+		for _, rec := range res.Recommendations {
+			for _, a := range rec.Action {
+				if action, ok := a.(*types.StoragePlacementAction); ok {
+					return action.Destination, nil
+				}
+			}
+		}
+	}
+
+	pc := property.DefaultCollector(c)
+
+	var p mo.StoragePod
+	if err = pc.RetrieveOne(ctx, pod, []string{"childEntity"}, &p); err != nil {
+		return pod, err
+	}
+	if len(p.ChildEntity) == 0 {
+		return pod, fmt.Errorf("datastore cluster has no datastores")
+	}
+
+	var datastores []mo.Datastore
+	if err = pc.Retrieve(ctx, p.ChildEntity, []string{"summary"}, &datastores); err != nil {
+		return pod, err
+	}
+
+	sort.Slice(datastores, func(i, j int) bool { return datastores[i].Summary.FreeSpace > datastores[j].Summary.FreeSpace })
+	return datastores[0].Self, nil
+}
+
+//
+// Planner builds the moves from the command line. Target holds the -host/-pool/-datastore/-datastore-cluster
+// destination that applies to every VM, the evacuations fill in whatever it leaves open per VM.
+//
+
+type Planner struct {
+	c   *vim25.Client
+	pc  *property.Collector
+	ctx context.Context
+
+	host, pool, datastore, pod *types.ManagedObjectReference
+
+	// compute is the destination cluster or host's compute resource, computePool its root pool
+	compute, computePool *types.ManagedObjectReference
+}
+
+func (p *Planner) move(vm types.ManagedObjectReference, host, pool, ds *types.ManagedObjectReference) (Move, error) {
+	var m Move
+	var err error
+
+	if host == nil {
+		host = p.host
+	}
+	if pool == nil {
+		pool = p.pool
+	}
+	if pool == nil && p.compute != nil {
+
+		// A VM that changes cluster needs a pool there too. One that stays keeps its own pool.
+
+		var v mo.VirtualMachine
+		if err = p.pc.RetrieveOne(p.ctx, vm, []string{"resourcePool"}, &v); err != nil {
+			return m, err
+		}
+		var rp mo.ResourcePool
+		if v.ResourcePool != nil {
+			if err = p.pc.RetrieveOne(p.ctx, *v.ResourcePool, []string{"owner"}, &rp); err != nil {
+				return m, err
+			}
+		}
+		if rp.Owner != *p.compute {
+			pool = p.computePool
+		}
+	}
+	if ds == nil {
+		ds = p.datastore
+	}
+	if ds == nil && p.pod != nil {
+		ref, err := podDatastore(p.ctx, p.c, *p.pod, vm)
+		if err != nil {
+			return m, err
+		}
+		ds = &ref
+	}
+
+	if m.VM, err = pathOf(p.ctx, p.c, &vm); err != nil {
+		return m, err
+	}
+	if m.Host, err = pathOf(p.ctx, p.c, host); err != nil {
+		return m, err
+	}
+	if m.Pool, err = pathOf(p.ctx, p.c, pool); err != nil {
+		return m, err
+	}
+	m.Datastore, err = pathOf(p.ctx, p.c, ds)
+
+	return m, err
+}
+
+//
+// evacuateHost moves every VM off a host. Unless a destination host was given, each VM goes to the connected
+// host with the least memory in use, counting the VMs already planned to go there. The hosts are those of the
+// -cluster destination, or else the other hosts of the source host's own cluster.
+//
+
+func (p *Planner) evacuateHost(source types.ManagedObjectReference) ([]Move, error) {
+	var h mo.HostSystem
+	if err := p.pc.RetrieveOne(p.ctx, source, []string{"name", "parent", "vm"}, &h); err != nil {
+		return nil, err
+	}
+
+	var vms []mo.VirtualMachine
+	if len(h.Vm) > 0 {
+		if err := p.pc.Retrieve(p.ctx, h.Vm, []string{"name", "config.template", "summary.config.memorySizeMB"}, &vms); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+
+	var candidates []mo.HostSystem
+	if p.host == nil {
+		cluster := *h.Parent
+		if p.compute != nil {
+			cluster = *p.compute
+		}
+
+		var cr mo.ComputeResource
+		if err := p.pc.RetrieveOne(p.ctx, cluster, []string{"name", "host"}, &cr); err != nil {
+			return nil, err
+		}
+
+		var hosts []mo.HostSystem
+		if err := p.pc.Retrieve(p.ctx, cr.Host, []string{"name", "runtime", "summary.quickStats"}, &hosts); err != nil {
+			return nil, err
+		}
+		for _, c := range hosts {
+			if c.Self != source && c.Runtime.ConnectionState == types.HostSystemConnectionStateConnected && !c.Runtime.InMaintenanceMode {
+				candidates = append(candidates, c)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%s has no other connected host to take the VMs of %s", cr.Name, h.Name)
+		}
+	}
+
+	used := make(map[types.ManagedObjectReference]int64)
+	for _, c := range candidates {
+		used[c.Self] = int64(c.Summary.QuickStats.OverallMemoryUsage)
+	}
+
+	var moves []Move
+	for _, vm := range vms {
+		if vm.Config != nil && vm.Config.Template {
+			continue
+		}
+
+		var host *types.ManagedObjectReference
+		if len(candidates) > 0 {
+			best := candidates[0].Self
+			for _, c := range candidates {
+				if used[c.Self] < used[best] {
+					best = c.Self
+				}
+			}
+			used[best] += int64(vm.Summary.Config.MemorySizeMB)
+			host = &best
+		}
+
+		m, err := p.move(vm.Self, host, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+
+	return moves, nil
+}
+
+//
+// evacuateDatastore moves every VM off a datastore. Unless a destination was given, each VM goes to the datastore
+// its host can see with the most free space, less what the VMs already planned to go there take up.
+//
+
+func (p *Planner) evacuateDatastore(source types.ManagedObjectReference) ([]Move, error) {
+	var ds mo.Datastore
+	if err := p.pc.RetrieveOne(p.ctx, source, []string{"name", "vm"}, &ds); err != nil {
+		return nil, err
+	}
+
+	var vms []mo.VirtualMachine
+	if len(ds.Vm) > 0 {
+		if err := p.pc.Retrieve(p.ctx, ds.Vm, []string{"name", "runtime.host", "summary.storage"}, &vms); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+
+	free := make(map[types.ManagedObjectReference]int64)
+
+	var moves []Move
+	for _, vm := range vms {
+		var target *types.ManagedObjectReference
+
+		if p.datastore == nil && p.pod == nil {
+			if vm.Runtime.Host == nil {
+				return nil, fmt.Errorf("%s is on no host, cannot choose a datastore for it", vm.Name)
+			}
+
+			var h mo.HostSystem
+			if err := p.pc.RetrieveOne(p.ctx, *vm.Runtime.Host, []string{"datastore"}, &h); err != nil {
+				return nil, err
+			}
+
+			var candidates []mo.Datastore
+			if err := p.pc.Retrieve(p.ctx, h.Datastore, []string{"summary"}, &candidates); err != nil {
+				return nil, err
+			}
+
+			for _, c := range candidates {
+				if c.Self == source || !c.Summary.Accessible || c.Summary.MaintenanceMode == string(types.DatastoreSummaryMaintenanceModeStateInMaintenance) {
+					continue
+				}
+				if _, ok := free[c.Self]; !ok {
+					free[c.Self] = c.Summary.FreeSpace
+				}
+				if target == nil || free[c.Self] > free[*target] {
+					ref := c.Self
+					target = &ref
+				}
+			}
+
+			if target == nil {
+				return nil, fmt.Errorf("the host of %s sees no other datastore", vm.Name)
+			}
+			if vm.Summary.Storage != nil {
+				free[*target] -= vm.Summary.Storage.Committed
+			}
+		}
+
+		m, err := p.move(vm.Self, nil, nil, target)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+
+	return moves, nil
+}
+
+//
+// Migration is a move resolved against the inventory, ready to be checked and run
+//
+
+type Migration struct {
+	Move
+	VMRef types.ManagedObjectReference
+	Spec  types.VirtualMachineRelocateSpec
+	From  string
+	Skip  string
+}
+
+func resolve(ctx context.Context, c *vim25.Client, m Move) (*Migration, error) {
+	mg := &Migration{Move: m}
+	var err error
+
+	if mg.VMRef, err = findObject(ctx, c, "VirtualMachine", m.VM); err != nil {
+		return nil, err
+	}
+
+	lookup := func(kind, arg string) (*types.ManagedObjectReference, error) {
+		if arg == "" {
+			return nil, nil
+		}
+		ref, err := findObject(ctx, c, kind, arg)
+		return &ref, err
+	}
+
+	if mg.Spec.Host, err = lookup("HostSystem", m.Host); err != nil {
+		return nil, err
+	}
+	if mg.Spec.Pool, err = lookup("ResourcePool", m.Pool); err != nil {
+		return nil, err
+	}
+	if mg.Spec.Datastore, err = lookup("Datastore", m.Datastore); err != nil {
+		return nil, err
+	}
+
+	// Where the VM is now, to print and to spot moves that are already done
+
+	pc := property.DefaultCollector(c)
+
+	var vm mo.VirtualMachine
+	if err = pc.RetrieveOne(ctx, mg.VMRef, []string{"runtime.host", "datastore"}, &vm); err != nil {
+		return nil, err
+	}
+
+	var refs []types.ManagedObjectReference
+	if vm.Runtime.Host != nil {
+		refs = append(refs, *vm.Runtime.Host)
+	}
+	refs = append(refs, vm.Datastore...)
+
+	var names []string
+	if len(refs) > 0 {
+		var entities []mo.ManagedEntity
+		if err = pc.Retrieve(ctx, refs, []string{"name"}, &entities); err != nil {
+			return nil, err
+		}
+		for _, e := range entities {
+			names = append(names, e.Name)
+		}
+	}
+	mg.From = strings.Join(names, ", ")
+
+	sameHost := mg.Spec.Host == nil || (vm.Runtime.Host != nil && *vm.Runtime.Host == *mg.Spec.Host)
+	sameDatastore := mg.Spec.Datastore == nil || (len(vm.Datastore) == 1 && vm.Datastore[0] == *mg.Spec.Datastore)
+	if sameHost && sameDatastore && mg.Spec.Pool == nil {
+		mg.Skip = "already there"
+	}
+
+	return mg, nil
+}
+
+// to describes the destination of a migration, by name
+func (mg *Migration) to() string {
+	var parts []string
+	for _, p := range []string{mg.Host, mg.Datastore} {
+		if p != "" {
+			parts = append(parts, path.Base(p))
+		}
+	}
+	if mg.Pool != "" && mg.Host == "" {
+		parts = append(parts, "pool "+path.Join(path.Base(path.Dir(mg.Pool)), path.Base(mg.Pool)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+//
+// check runs the VM provisioning checker on a migration and returns its errors and warnings. vCenter runs the
+// same checks the vMotion wizard does; where the checker is not available the move is let through with a warning.
+//
+
+func check(ctx context.Context, c *vim25.Client, mg *Migration) ([]string, []string) {
+	if c.ServiceContent.VmProvisioningChecker == nil {
+		return nil, []string{"no VM provisioning checker, compatibility not checked"}
+	}
+
+	req := types.CheckRelocate_Task{
+		This: *c.ServiceContent.VmProvisioningChecker,
+		Vm:   mg.VMRef,
+		Spec: mg.Spec,
+	}
+
+	res, err := methods.CheckRelocate_Task(ctx, c, &req)
+	var info *types.TaskInfo
+	if err == nil {
+		info, err = object.NewTask(c, res.Returnval).WaitForResult(ctx, nil)
+	}
+	if err != nil {
+		return nil, []string{fmt.Sprintf("compatibility not checked: %s", err)}
+	}
+
+	var errs, warnings []string
+	if results, ok := info.Result.(types.ArrayOfCheckResult); ok {
+		for _, r := range results.CheckResult {
+			for _, e := range r.Error {
+				errs = append(errs, e.LocalizedMessage)
+			}
+			for _, w := range r.Warning {
+				warnings = append(warnings, w.LocalizedMessage)
+			}
+		}
+	}
+
+	return errs, warnings
+}
+
+//
+// progressLogger prints the completion percentage of a task each time it moves on by 10% or more
+//
+
+type progressLogger struct {
+	name string
+	ch   chan progress.Report
+	done chan struct{}
+}
+
+func newProgressLogger(name string) *progressLogger {
+	p := &progressLogger{name: name, ch: make(chan progress.Report), done: make(chan struct{})}
+
+	go func() {
+		defer close(p.done)
+		last := float32(0)
+		for r := range p.ch {
+			if r.Percentage() >= last+10 && r.Percentage() < 100 {
+				last = r.Percentage()
+				fmt.Printf("  %s: %.0f%%\n", p.name, last)
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *progressLogger) Sink() chan<- progress.Report { return p.ch }
+
+// Wait returns once the task has finished and its last report was printed
+func (p *progressLogger) Wait() { <-p.done }
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var vmArgs stringList
+	var host, cluster, pool, datastore, pod, evacuateHost, evacuateDatastore, planIn, planOut string
+	var workers int
+	var apply bool
+
+	flag.Var(&vmArgs, "vm", "VM to migrate, by name or inventory path (repeatable)")
+	flag.StringVar(&host, "host", "", "destination host")
+	flag.StringVar(&cluster, "cluster", "", "destination cluster (DRS picks the host)")
+	flag.StringVar(&pool, "pool", "", "destination resource pool")
+	flag.StringVar(&datastore, "datastore", "", "destination datastore")
+	flag.StringVar(&pod, "datastore-cluster", "", "destination datastore cluster (Storage DRS picks the datastore)")
+	flag.StringVar(&evacuateHost, "evacuate-host", "", "migrate every VM off this host")
+	flag.StringVar(&evacuateDatastore, "evacuate-datastore", "", "migrate every VM off this datastore")
+	flag.StringVar(&planIn, "plan", "", "run the moves in this plan file instead of planning them")
+	flag.StringVar(&planOut, "plan-out", "", "write the plan to this file rather than running it")
+	flag.IntVar(&workers, "workers", 2, "number of migrations to run at once")
+	flag.BoolVar(&apply, "apply", false, "run the migrations rather than only checking them")
+	flag.Parse()
+
+	sources := 0
+	for _, set := range []bool{len(vmArgs) > 0, evacuateHost != "", evacuateDatastore != "", planIn != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		fmt.Printf("usage: migrate-vm (-vm vm [-vm vm] | -evacuate-host h | -evacuate-datastore ds | -plan file) [-host h | -cluster c] [-pool p] [-datastore ds | -datastore-cluster dsc] [-plan-out file] [-apply]\n")
+		return
+	}
+	if host != "" && cluster != "" || datastore != "" && pod != "" {
+		fmt.Printf("give either -host or -cluster, and either -datastore or -datastore-cluster\n")
+		return
+	}
+	if len(vmArgs) > 0 && host == "" && cluster == "" && pool == "" && datastore == "" && pod == "" {
+		fmt.Printf("-vm needs a destination: -host, -cluster, -pool, -datastore or -datastore-cluster\n")
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	//
+	// Build the plan - read it from a file, or work it out from the destination flags
+	//
+
+	var plan Plan
+
+	if planIn != "" {
+		if plan, err = readPlan(planIn); err != nil {
+			fmt.Printf("Could not read plan %s, error %v\n", planIn, err)
+			return
+		}
+	} else {
+		p := &Planner{c: c, pc: property.DefaultCollector(c), ctx: ctx}
+
+		lookup := func(kind, arg string) *types.ManagedObjectReference {
+			if arg == "" || err != nil {
+				return nil
+			}
+			var ref types.ManagedObjectReference
+			ref, err = findObject(ctx, c, kind, arg)
+			return &ref
+		}
+
+		p.host = lookup("HostSystem", host)
+		clusterRef := lookup("ClusterComputeResource", cluster)
+		p.pool = lookup("ResourcePool", pool)
+		p.datastore = lookup("Datastore", datastore)
+		p.pod = lookup("StoragePod", pod)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+
+		if clusterRef != nil {
+			p.compute, p.computePool, err = computeOf(ctx, c, *clusterRef)
+		} else if p.host != nil {
+			p.compute, p.computePool, err = computeOf(ctx, c, *p.host)
+		}
+		if err != nil {
+			fmt.Printf("Could not find the destination resource pool, error %v\n", err)
+			return
+		}
+
+		switch {
+		case evacuateHost != "":
+			var ref types.ManagedObjectReference
+			if ref, err = findObject(ctx, c, "HostSystem", evacuateHost); err == nil {
+				plan.Moves, err = p.evacuateHost(ref)
+			}
+		case evacuateDatastore != "":
+			var ref types.ManagedObjectReference
+			if ref, err = findObject(ctx, c, "Datastore", evacuateDatastore); err == nil {
+				plan.Moves, err = p.evacuateDatastore(ref)
+			}
+		default:
+			for _, arg := range vmArgs {
+				var ref types.ManagedObjectReference
+				if ref, err = findObject(ctx, c, "VirtualMachine", arg); err != nil {
+					break
+				}
+				var m Move
+				if m, err = p.move(ref, nil, nil, nil); err != nil {
+					break
+				}
+				plan.Moves = append(plan.Moves, m)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Could not plan the migration, error %v\n", err)
+			return
+		}
+	}
+
+	if planOut != "" {
+		if err = writePlan(planOut, plan); err != nil {
+			fmt.Printf("Could not write plan %s, error %v\n", planOut, err)
+			return
+		}
+		fmt.Printf("Wrote %d move(s) to %s\n", len(plan.Moves), planOut)
+		return
+	}
+
+	if len(plan.Moves) == 0 {
+		fmt.Printf("Nothing to migrate\n")
+		return
+	}
+
+	//
+	// Resolve and check every move before running any of them
+	//
+
+	fmt.Println()
+
+	var todo []*Migration
+	blocked := 0
+
+	for _, m := range plan.Moves {
+		mg, err := resolve(ctx, c, m)
+		if err != nil {
+			fmt.Printf("! %s: %v\n", m.VM, err)
+			blocked++
+			continue
+		}
+
+		name := path.Base(mg.VM)
+		if mg.Skip != "" {
+			fmt.Printf("= %s %s\n", name, mg.Skip)
+			continue
+		}
+
+		fmt.Printf("~ %s: %s -> %s\n", name, mg.From, mg.to())
+
+		errs, warnings := check(ctx, c, mg)
+		for _, w := range warnings {
+			fmt.Printf("    warning: %s\n", w)
+		}
+		for _, e := range errs {
+			fmt.Printf("    error: %s\n", e)
+		}
+		if len(errs) > 0 {
+			blocked++
+			continue
+		}
+
+		todo = append(todo, mg)
+	}
+
+	if blocked > 0 {
+		fmt.Printf("\n%d move(s) cannot go ahead\n", blocked)
+	}
+
+	if !apply {
+		fmt.Printf("\nDry run - re-run with -apply to migrate\n")
+		return
+	}
+	if len(todo) == 0 {
+		return
+	}
+
+	fmt.Println()
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+
+	for _, mg := range todo {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(mg *Migration) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			name := path.Base(mg.VM)
+
+			task, err := object.NewVirtualMachine(c, mg.VMRef).Relocate(ctx, mg.Spec, types.VirtualMachineMovePriorityDefaultPriority)
+			if err == nil {
+				p := newProgressLogger(name)
+				_, err = task.WaitForResult(ctx, p)
+				p.Wait()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Printf("! %s migration failed, error %v\n", name, err)
+				failed++
+				return
+			}
+			fmt.Printf("  %s: migrated to %s\n", name, mg.to())
+		}(mg)
+	}
+
+	wg.Wait()
+
+	fmt.Printf("\n%d of %d migration(s) done\n", len(todo)-failed, len(todo))
+	if failed > 0 || blocked > 0 {
+		os.Exit(1)
+	}
+}
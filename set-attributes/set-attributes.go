@@ -0,0 +1,342 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and list, set and clear custom attributes (custom fields) on inventory objects
+//
+//			  -list                                          list the custom attribute definitions
+//			  -object /DC0/vm/web-* -set owner=alice          set a value (repeat -set and -object as needed)
+//			  -object /DC0/host/*/* -clear window             clear a value
+//			  -create                                        define any -set attribute that does not exist yet
+//
+//			Objects are absolute inventory paths and may contain wildcards. Values that are already
+//			set as requested are left alone, so a run can be repeated. Without -apply the plan is only printed.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// customValue returns the value an object holds for a custom attribute, or "" when it is not set
+func customValue(values []types.BaseCustomFieldValue, key int32) string {
+	value := ""
+	for _, v := range values {
+		if sv, ok := v.(*types.CustomFieldStringValue); ok && sv.Key == key {
+			value = sv.Value
+		}
+	}
+	return value
+}
+
+//
+// findField returns the definition of an attribute that can be used on the given type - a name can be defined
+// once per managed object type as well as globally, so the type specific one wins
+//
+
+func findField(fields []types.CustomFieldDef, name, moType string) *types.CustomFieldDef {
+	var global *types.CustomFieldDef
+
+	for i, def := range fields {
+		if def.Name != name {
+			continue
+		}
+		if def.ManagedObjectType == moType {
+			return &fields[i]
+		}
+		if def.ManagedObjectType == "" {
+			global = &fields[i]
+		}
+	}
+
+	return global
+}
+
+func listFields(fields []types.CustomFieldDef) {
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 4, ' ', 0)
+	fmt.Printf("\n*** Custom Attributes ***\n")
+	fmt.Printf("-------------------------\n\n")
+	fmt.Fprintf(tw, "Name\tKey\tType\tApplies To\n")
+	fmt.Fprintf(tw, "----\t---\t----\t----------\n")
+
+	for _, def := range fields {
+		appliesTo := def.ManagedObjectType
+		if appliesTo == "" {
+			appliesTo = "Global"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", def.Name, def.Key, def.Type, appliesTo)
+	}
+
+	_ = tw.Flush()
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var list, create, apply bool
+	var objects, sets, clears stringList
+	flag.BoolVar(&list, "list", false, "list the custom attribute definitions")
+	flag.Var(&objects, "object", "inventory path of the objects to update, wildcards allowed (repeatable)")
+	flag.Var(&sets, "set", "name=value to set on the objects (repeatable)")
+	flag.Var(&clears, "clear", "name of an attribute to clear on the objects (repeatable)")
+	flag.BoolVar(&create, "create", false, "define -set attributes that do not exist yet")
+	flag.BoolVar(&apply, "apply", false, "make the changes rather than only printing them")
+	flag.Parse()
+
+	if !list && (len(objects) == 0 || len(sets)+len(clears) == 0) {
+		fmt.Printf("usage: set-attributes -list | -object path [-object path] [-set name=value] [-clear name] [-create] [-apply]\n")
+		return
+	}
+
+	values := make(map[string]string)
+	var names []string
+	for _, kv := range sets {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			fmt.Printf("-set wants name=value, got %q\n", kv)
+			return
+		}
+		values[kv[:i]] = kv[i+1:]
+		names = append(names, kv[:i])
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	//
+	// -- https://pkg.go.dev/github.com/vmware/govmomi/object#CustomFieldsManager
+	//
+
+	m, err := object.GetCustomFieldsManager(c)
+	if err != nil {
+		fmt.Printf("Custom attributes need a vCenter connection, error %v\n", err)
+		return
+	}
+
+	fields, err := m.Field(ctx)
+	if err != nil {
+		fmt.Printf("Could not get custom attribute definitions, error %v\n", err)
+		return
+	}
+
+	if list {
+		listFields(fields)
+		return
+	}
+
+	//
+	// Expand the inventory paths, then fetch the current values of every object in one call
+	//
+
+	finder := find.NewFinder(c)
+
+	paths := make(map[types.ManagedObjectReference]string)
+	var refs []types.ManagedObjectReference
+
+	for _, path := range objects {
+		elements, err := finder.ManagedObjectList(ctx, path)
+		if err != nil || len(elements) == 0 {
+			fmt.Printf("! no objects found at %s\n", path)
+			continue
+		}
+		for _, e := range elements {
+			ref := e.Object.Reference()
+			if _, ok := paths[ref]; !ok {
+				paths[ref] = e.Path
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	if len(refs) == 0 {
+		return
+	}
+
+	var entities []mo.ManagedEntity
+	err = property.DefaultCollector(c).Retrieve(ctx, refs, []string{"customValue"}, &entities)
+	if err != nil {
+		fmt.Printf("Could not get current custom attribute values, error %v\n", err)
+		return
+	}
+
+	current := make(map[types.ManagedObjectReference][]types.BaseCustomFieldValue)
+	moTypes := make(map[string]bool)
+	for _, e := range entities {
+		current[e.Self] = e.CustomValue
+		moTypes[e.Self.Type] = true
+	}
+
+	//
+	// With -create, a missing attribute is defined for the type of the objects, or globally
+	// when the objects are of more than one type
+	//
+
+	if create {
+		moType := ""
+		if len(moTypes) == 1 {
+			for t := range moTypes {
+				moType = t
+			}
+		}
+
+		for _, name := range names {
+			missing := false
+			for t := range moTypes {
+				if findField(fields, name, t) == nil {
+					missing = true
+				}
+			}
+			if !missing {
+				continue
+			}
+
+			// Until it is defined no object holds a value for it, so the dry run plans every -set as a change
+
+			def := &types.CustomFieldDef{Name: name, ManagedObjectType: moType}
+			if apply {
+				def, err = m.Add(ctx, name, moType, nil, nil)
+				if err != nil {
+					fmt.Printf("Could not define custom attribute %s, error %v\n", name, err)
+					return
+				}
+			}
+			if moType == "" {
+				fmt.Printf("+ attribute %s (Global)\n", name)
+			} else {
+				fmt.Printf("+ attribute %s (%s)\n", name, moType)
+			}
+			fields = append(fields, *def)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return paths[refs[i]] < paths[refs[j]] })
+
+	for _, ref := range refs {
+		path := paths[ref]
+
+		for _, name := range names {
+			def := findField(fields, name, ref.Type)
+			if def == nil {
+				fmt.Printf("! %s is not defined for %s (use -create)\n", name, ref.Type)
+				continue
+			}
+
+			old := customValue(current[ref], def.Key)
+			if old == values[name] {
+				fmt.Printf("= %s %s=%s\n", path, name, old)
+				continue
+			}
+
+			fmt.Printf("~ %s %s: %q -> %q\n", path, name, old, values[name])
+			if !apply {
+				continue
+			}
+			if err = m.Set(ctx, ref, def.Key, values[name]); err != nil {
+				fmt.Printf("Could not set %s on %s, error %v\n", name, path, err)
+			}
+		}
+
+		for _, name := range clears {
+			def := findField(fields, name, ref.Type)
+			if def == nil || customValue(current[ref], def.Key) == "" {
+				fmt.Printf("= %s %s (not set)\n", path, name)
+				continue
+			}
+
+			// Setting an empty value removes it from the object
+
+			fmt.Printf("- %s %s\n", path, name)
+			if !apply {
+				continue
+			}
+			if err = m.Set(ctx, ref, def.Key, ""); err != nil {
+				fmt.Printf("Could not clear %s on %s, error %v\n", name, path, err)
+			}
+		}
+	}
+
+	if !apply {
+		fmt.Printf("\nDry run - re-run with -apply to make these changes\n")
+	}
+}
@@ -0,0 +1,839 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and list or manage the DRS VM/host groups and VM-VM / VM-host rules of a cluster
+//
+//			  (no change flags)                                     list the groups and rules (-cluster pattern)
+//			  -set-vm-group gpu-nodes -vm a -node k8s-worker-01      create a VM group, or set its members
+//			  -set-host-group gpu-hosts -host 'esx-gpu-*'            create a host group, or set its members
+//			  -set-rule pin-gpu -rule-type vm-host -policy must      VMs of -vm-group must/should (not) run on
+//			      [-vm-group gpu-nodes -host-group gpu-hosts]        the hosts of -host-group
+//			  -set-rule keep-apart -rule-type anti-affinity -vm a -vm b   keep VMs apart (or together, affinity)
+//			  -delete-group name / -delete-rule name                 remove a group or rule (repeatable)
+//
+//			A VM-host rule uses the groups set in the same run unless -vm-group / -host-group are given, so the
+//			VMs behind a set of Kubernetes nodes can be pinned to the GPU hosts found by get-gpu-candidates
+//			in one command. All the changes are made in one cluster reconfigure, and are only printed until
+//			-apply is given.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//
+// findObject resolves an inventory object of the given type by inventory path (starts with "/") or by name
+//
+
+func findObject(ctx context.Context, c *vim25.Client, kind, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		elements, err := find.NewFinder(c).ManagedObjectList(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		for _, e := range elements {
+			if e.Object.Reference().Type == kind {
+				return e.Object.Reference(), nil
+			}
+		}
+		return none, fmt.Errorf("no %s at %s", kind, arg)
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{kind}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{kind}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no %s named %s", kind, arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d objects of type %s are named %s, use the path instead:\n  %s", len(refs), kind, arg, strings.Join(paths, "\n  "))
+}
+
+//
+// nodeVMs resolves the -node arguments to the members of the -set-vm-group VM group
+//
+
+func nodeVMs(ctx context.Context, c *vim25.Client, kubeconfig string, names []string) ([]types.ManagedObjectReference, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	finder := find.NewFinder(c)
+	index := object.NewSearchIndex(c)
+	var refs []types.ManagedObjectReference
+
+	for _, name := range names {
+		node, err := clientSet.CoreV1().Nodes().Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get node %s: %s", name, err)
+		}
+
+		if uuid := strings.TrimPrefix(node.Spec.ProviderID, "vsphere://"); uuid != node.Spec.ProviderID {
+			ref, err := index.FindByUuid(ctx, nil, uuid, true, nil)
+			if err != nil {
+				return nil, err
+			}
+			if ref != nil {
+				refs = append(refs, ref.Reference())
+				continue
+			}
+		}
+
+		vms, err := finder.VirtualMachineList(ctx, "/*/vm/.../"+name)
+		if err != nil || len(vms) != 1 {
+			return nil, fmt.Errorf("could not find a VM for node %s", name)
+		}
+		refs = append(refs, vms[0].Reference())
+	}
+
+	return refs, nil
+}
+
+//
+// Names resolves the VMs and hosts referenced by groups and rules, so members can be printed and compared
+//
+
+type Names map[types.ManagedObjectReference]string
+
+func (n Names) resolve(ctx context.Context, c *vim25.Client, refs []types.ManagedObjectReference) error {
+	var missing []types.ManagedObjectReference
+	for _, ref := range refs {
+		if _, ok := n[ref]; !ok {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var content []types.ObjectContent
+	if err := property.DefaultCollector(c).Retrieve(ctx, missing, []string{"name"}, &content); err != nil {
+		return err
+	}
+	for _, oc := range content {
+		for _, prop := range oc.PropSet {
+			if name, ok := prop.Val.(string); ok {
+				n[oc.Obj] = name
+			}
+		}
+	}
+	return nil
+}
+
+// list returns the sorted names of refs, joined for printing
+func (n Names) list(refs []types.ManagedObjectReference) string {
+	var names []string
+	for _, ref := range refs {
+		names = append(names, n[ref])
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// groupMembers returns the VMs of a VM group or the hosts of a host group
+func groupMembers(g types.BaseClusterGroupInfo) []types.ManagedObjectReference {
+	switch g := g.(type) {
+	case *types.ClusterVmGroup:
+		return g.Vm
+	case *types.ClusterHostGroup:
+		return g.Host
+	}
+	return nil
+}
+
+func groupKind(g types.BaseClusterGroupInfo) string {
+	switch g.(type) {
+	case *types.ClusterVmGroup:
+		return "VM"
+	case *types.ClusterHostGroup:
+		return "Host"
+	}
+	return "-"
+}
+
+//
+// policies maps -policy to a VM-host rule - whether the VMs are kept on or off the host group, and whether
+// DRS and HA must honour the rule or only should
+//
+
+var policies = map[string]struct {
+	affine    bool
+	mandatory bool
+}{
+	"must":       {true, true},
+	"should":     {true, false},
+	"must-not":   {false, true},
+	"should-not": {false, false},
+}
+
+// ruleKind names the type of a rule, and describes what it applies to
+func ruleKind(r types.BaseClusterRuleInfo, names Names) (string, string) {
+	switch r := r.(type) {
+	case *types.ClusterAffinityRuleSpec:
+		return "VM-VM affinity", names.list(r.Vm)
+	case *types.ClusterAntiAffinityRuleSpec:
+		return "VM-VM anti-affinity", names.list(r.Vm)
+	case *types.ClusterVmHostRuleInfo:
+		verb := "should"
+		if info := r.GetClusterRuleInfo(); isTrue(info.Mandatory) {
+			verb = "must"
+		}
+		if r.AffineHostGroupName != "" {
+			return "VM-Host", fmt.Sprintf("%s %s run on %s", r.VmGroupName, verb, r.AffineHostGroupName)
+		}
+		return "VM-Host", fmt.Sprintf("%s %s not run on %s", r.VmGroupName, verb, r.AntiAffineHostGroupName)
+	case *types.ClusterDependencyRuleInfo:
+		return "VM-VM dependency", fmt.Sprintf("%s depends on %s", r.VmGroup, r.DependsOnVmGroup)
+	}
+	return "-", ""
+}
+
+func yesNo(b *bool) string {
+	if b == nil {
+		return "-"
+	}
+	if *b {
+		return "yes"
+	}
+	return "no"
+}
+
+// isTrue reads an optional flag, which vCenter leaves unset or returns as false interchangeably
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// ruleRefs collects the VMs and hosts of every group, and the VMs named directly by VM-VM rules
+func ruleRefs(config *types.ClusterConfigInfoEx) []types.ManagedObjectReference {
+	var refs []types.ManagedObjectReference
+	for _, g := range config.Group {
+		refs = append(refs, groupMembers(g)...)
+	}
+	for _, r := range config.Rule {
+		switch r := r.(type) {
+		case *types.ClusterAffinityRuleSpec:
+			refs = append(refs, r.Vm...)
+		case *types.ClusterAntiAffinityRuleSpec:
+			refs = append(refs, r.Vm...)
+		}
+	}
+	return refs
+}
+
+func printCluster(cluster mo.ClusterComputeResource, names Names) {
+	tw := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+
+	config, _ := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx)
+
+	fmt.Printf("\n*** Cluster %s ***\n", cluster.Name)
+
+	fmt.Printf("\nVM/Host Groups\n--------------\n")
+	if config == nil || len(config.Group) == 0 {
+		fmt.Printf("none\n")
+	} else {
+		groups := config.Group
+		sort.Slice(groups, func(i, j int) bool {
+			return groups[i].GetClusterGroupInfo().Name < groups[j].GetClusterGroupInfo().Name
+		})
+		fmt.Fprintf(tw, "Name\tType\tMembers\n")
+		for _, g := range groups {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", g.GetClusterGroupInfo().Name, groupKind(g), names.list(groupMembers(g)))
+		}
+		_ = tw.Flush()
+	}
+
+	fmt.Printf("\nRules\n-----\n")
+	if config == nil || len(config.Rule) == 0 {
+		fmt.Printf("none\n")
+		return
+	}
+
+	rules := config.Rule
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].GetClusterRuleInfo().Name < rules[j].GetClusterRuleInfo().Name
+	})
+	fmt.Fprintf(tw, "Name\tType\tEnabled\tMandatory\tCompliant\tApplies To\n")
+	for _, r := range rules {
+		info := r.GetClusterRuleInfo()
+		kind, detail := ruleKind(r, names)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", info.Name, kind, yesNo(info.Enabled), yesNo(info.Mandatory), yesNo(info.InCompliance), detail)
+	}
+	_ = tw.Flush()
+}
+
+//
+// Change is a planned cluster reconfiguration - Spec collects every group and rule change for the one
+// reconfigure, Lines is what gets printed and Problems are the reasons it cannot go ahead
+//
+
+type Change struct {
+	Spec     types.ClusterConfigSpecEx
+	Lines    []string
+	Problems []string
+}
+
+func (ch *Change) line(format string, args ...interface{}) {
+	ch.Lines = append(ch.Lines, fmt.Sprintf(format, args...))
+}
+
+func (ch *Change) problem(format string, args ...interface{}) {
+	ch.Problems = append(ch.Problems, fmt.Sprintf(format, args...))
+}
+
+func (ch *Change) group(op types.ArrayUpdateOperation, info types.BaseClusterGroupInfo) {
+	spec := types.ClusterGroupSpec{ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: op}, Info: info}
+	if op == types.ArrayUpdateOperationRemove {
+		spec.RemoveKey = info.GetClusterGroupInfo().Name
+		spec.Info = nil
+	}
+	ch.Spec.GroupSpec = append(ch.Spec.GroupSpec, spec)
+}
+
+func (ch *Change) rule(op types.ArrayUpdateOperation, info types.BaseClusterRuleInfo) {
+	spec := types.ClusterRuleSpec{ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: op}, Info: info}
+	if op == types.ArrayUpdateOperationRemove {
+		spec.RemoveKey = info.GetClusterRuleInfo().Key
+		spec.Info = nil
+	}
+	ch.Spec.RulesSpec = append(ch.Spec.RulesSpec, spec)
+}
+
+// sameMembers compares two member lists regardless of order
+func sameMembers(a, b []types.ManagedObjectReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[types.ManagedObjectReference]bool)
+	for _, ref := range a {
+		seen[ref] = true
+	}
+	for _, ref := range b {
+		if !seen[ref] {
+			return false
+		}
+	}
+	return true
+}
+
+func findGroup(config *types.ClusterConfigInfoEx, name string) types.BaseClusterGroupInfo {
+	for _, g := range config.Group {
+		if g.GetClusterGroupInfo().Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func findRule(config *types.ClusterConfigInfoEx, name string) types.BaseClusterRuleInfo {
+	for _, r := range config.Rule {
+		if r.GetClusterRuleInfo().Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+//
+// setGroup plans the creation of a VM or host group, or an update of its members
+//
+
+func (ch *Change) setGroup(config *types.ClusterConfigInfoEx, info types.BaseClusterGroupInfo, names Names) {
+	name := info.GetClusterGroupInfo().Name
+	kind := groupKind(info)
+	members := groupMembers(info)
+
+	if len(members) == 0 {
+		ch.problem("%s group %s needs at least one member", kind, name)
+		return
+	}
+
+	existing := findGroup(config, name)
+	switch {
+	case existing == nil:
+		ch.group(types.ArrayUpdateOperationAdd, info)
+		ch.line("+ %s group %s (%s)", kind, name, names.list(members))
+	case groupKind(existing) != kind:
+		ch.problem("%s exists as a %s group", name, groupKind(existing))
+	case sameMembers(groupMembers(existing), members):
+		ch.line("= %s group %s unchanged", kind, name)
+	default:
+		ch.group(types.ArrayUpdateOperationEdit, info)
+		ch.line("~ %s group %s: %s -> %s", kind, name, names.list(groupMembers(existing)), names.list(members))
+	}
+}
+
+// sameRule compares the parts of a rule this tool sets
+func sameRule(a, b types.BaseClusterRuleInfo) bool {
+	ai, bi := a.GetClusterRuleInfo(), b.GetClusterRuleInfo()
+	if isTrue(ai.Enabled) != isTrue(bi.Enabled) || isTrue(ai.Mandatory) != isTrue(bi.Mandatory) {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *types.ClusterAffinityRuleSpec:
+		b, ok := b.(*types.ClusterAffinityRuleSpec)
+		return ok && sameMembers(a.Vm, b.Vm)
+	case *types.ClusterAntiAffinityRuleSpec:
+		b, ok := b.(*types.ClusterAntiAffinityRuleSpec)
+		return ok && sameMembers(a.Vm, b.Vm)
+	case *types.ClusterVmHostRuleInfo:
+		b, ok := b.(*types.ClusterVmHostRuleInfo)
+		return ok && a.VmGroupName == b.VmGroupName && a.AffineHostGroupName == b.AffineHostGroupName &&
+			a.AntiAffineHostGroupName == b.AntiAffineHostGroupName
+	}
+	return false
+}
+
+//
+// setRule plans the creation or update of a rule. An existing rule keeps its key, which is how vCenter
+// knows which rule is being edited; a rule cannot change type, so that has to be a delete and a create.
+//
+
+func (ch *Change) setRule(config *types.ClusterConfigInfoEx, info types.BaseClusterRuleInfo, names Names) {
+	rule := info.GetClusterRuleInfo()
+	kind, detail := ruleKind(info, names)
+
+	existing := findRule(config, rule.Name)
+	if existing == nil {
+		ch.rule(types.ArrayUpdateOperationAdd, info)
+		ch.line("+ %s rule %s: %s (enabled %s)", kind, rule.Name, detail, yesNo(rule.Enabled))
+		return
+	}
+
+	oldKind, oldDetail := ruleKind(existing, names)
+	if oldKind != kind {
+		ch.problem("rule %s exists as a %s rule - delete it first", rule.Name, oldKind)
+		return
+	}
+
+	if sameRule(existing, info) {
+		ch.line("= %s rule %s unchanged", kind, rule.Name)
+		return
+	}
+
+	rule.Key = existing.GetClusterRuleInfo().Key
+	rule.RuleUuid = existing.GetClusterRuleInfo().RuleUuid
+	ch.rule(types.ArrayUpdateOperationEdit, info)
+	ch.line("~ %s rule %s: %s (enabled %s) -> %s (enabled %s)", kind, rule.Name,
+		oldDetail, yesNo(existing.GetClusterRuleInfo().Enabled), detail, yesNo(rule.Enabled))
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var clusterArg, vmGroup, hostGroup, ruleName, ruleType, useVMGroup, useHostGroup, policy string
+	var vmArgs, nodes, hostArgs, deleteGroups, deleteRules stringList
+	var enabled, apply bool
+
+	var kubeconfig *string
+	if home := homedir.HomeDir(); home != "" {
+		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+	} else {
+		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	}
+
+	flag.StringVar(&clusterArg, "cluster", "", "cluster name or path (a name pattern when listing)")
+	flag.StringVar(&vmGroup, "set-vm-group", "", "create or update this VM group with the -vm / -node members")
+	flag.StringVar(&hostGroup, "set-host-group", "", "create or update this host group with the -host members")
+	flag.StringVar(&ruleName, "set-rule", "", "create or update this rule")
+	flag.StringVar(&ruleType, "rule-type", "vm-host", "rule type: vm-host, affinity or anti-affinity")
+	flag.StringVar(&useVMGroup, "vm-group", "", "VM group of a vm-host rule (default the -set-vm-group)")
+	flag.StringVar(&useHostGroup, "host-group", "", "host group of a vm-host rule (default the -set-host-group)")
+	flag.StringVar(&policy, "policy", "should", "vm-host rule policy: must, should, must-not or should-not")
+	flag.BoolVar(&enabled, "enabled", true, "create or leave the rule enabled")
+	flag.Var(&vmArgs, "vm", "VM name or path, for a VM group or a VM-VM rule (repeatable)")
+	flag.Var(&nodes, "node", "the VM behind this Kubernetes node, as -vm (repeatable)")
+	flag.Var(&hostArgs, "host", "host name or pattern in the cluster, for a host group (repeatable)")
+	flag.Var(&deleteGroups, "delete-group", "delete this VM or host group (repeatable)")
+	flag.Var(&deleteRules, "delete-rule", "delete this rule (repeatable)")
+	flag.BoolVar(&apply, "apply", false, "make the changes rather than only printing them")
+	flag.Parse()
+
+	changing := vmGroup != "" || hostGroup != "" || ruleName != "" || len(deleteGroups) > 0 || len(deleteRules) > 0
+
+	if changing && clusterArg == "" {
+		fmt.Printf("-cluster is needed to change groups or rules\n")
+		return
+	}
+
+	if _, ok := policies[policy]; !ok {
+		fmt.Printf("-policy must be must, should, must-not or should-not\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	pc := property.DefaultCollector(c)
+	names := make(Names)
+
+	//
+	// Without any change flags, list the groups and rules of every cluster matching -cluster
+	//
+
+	if !changing {
+		pattern := clusterArg
+		if pattern == "" {
+			pattern = "*"
+		}
+
+		v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"ClusterComputeResource"}, true)
+		if err != nil {
+			fmt.Printf("Could not create container view, error %v\n", err)
+			return
+		}
+
+		defer v.Destroy(ctx)
+
+		var clusters []mo.ClusterComputeResource
+		err = v.RetrieveWithFilter(ctx, []string{"ClusterComputeResource"}, []string{"name", "configurationEx"}, &clusters, property.Filter{"name": pattern})
+		if err != nil {
+			fmt.Printf("Could not get list of clusters, error %v\n", err)
+			return
+		}
+
+		sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+		for _, cluster := range clusters {
+			if config, ok := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+				if err = names.resolve(ctx, c, ruleRefs(config)); err != nil {
+					fmt.Printf("Could not resolve group members, error %v\n", err)
+					return
+				}
+			}
+			printCluster(cluster, names)
+		}
+		return
+	}
+
+	ref, err := findObject(ctx, c, "ClusterComputeResource", clusterArg)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+
+	var cluster mo.ClusterComputeResource
+	if err = pc.RetrieveOne(ctx, ref, []string{"name", "host", "configurationEx"}, &cluster); err != nil {
+		fmt.Printf("Could not retrieve cluster %s, error %v\n", clusterArg, err)
+		return
+	}
+
+	config, ok := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx)
+	if !ok {
+		fmt.Printf("%s has no cluster configuration\n", cluster.Name)
+		return
+	}
+
+	//
+	// Resolve the members - VMs by name or path plus the VMs behind -node, hosts matched against the
+	// cluster's own hosts
+	//
+
+	var vms []types.ManagedObjectReference
+	for _, arg := range vmArgs {
+		vm, err := findObject(ctx, c, "VirtualMachine", arg)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		vms = append(vms, vm)
+	}
+
+	if len(nodes) > 0 {
+		refs, err := nodeVMs(ctx, c, *kubeconfig, nodes)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		vms = append(vms, refs...)
+	}
+
+	if err = names.resolve(ctx, c, append(append(vms, cluster.Host...), ruleRefs(config)...)); err != nil {
+		fmt.Printf("Could not resolve names, error %v\n", err)
+		return
+	}
+
+	var hosts []types.ManagedObjectReference
+	for _, arg := range hostArgs {
+		matched := false
+		for _, h := range cluster.Host {
+			if ok, _ := path.Match(arg, names[h]); ok {
+				hosts = append(hosts, h)
+				matched = true
+			}
+		}
+		if !matched {
+			fmt.Printf("no host in %s matches %s\n", cluster.Name, arg)
+			return
+		}
+	}
+
+	//
+	// Plan the changes - deletions first, so a group or rule can be deleted and re-created in the same run
+	//
+
+	var ch Change
+
+	deletedRule := make(map[string]bool)
+	for _, name := range deleteRules {
+		r := findRule(config, name)
+		if r == nil {
+			ch.problem("no rule named %s", name)
+			continue
+		}
+		ch.rule(types.ArrayUpdateOperationRemove, r)
+		ch.line("- rule %s", name)
+		deletedRule[name] = true
+	}
+
+	deletedGroup := make(map[string]bool)
+	for _, name := range deleteGroups {
+		g := findGroup(config, name)
+		if g == nil {
+			ch.problem("no group named %s", name)
+			continue
+		}
+
+		// vCenter refuses to delete a group a rule still refers to
+
+		for _, r := range config.Rule {
+			if vr, ok := r.(*types.ClusterVmHostRuleInfo); ok && !deletedRule[vr.Name] &&
+				(vr.VmGroupName == name || vr.AffineHostGroupName == name || vr.AntiAffineHostGroupName == name) {
+				ch.problem("group %s is used by rule %s - delete the rule too", name, vr.Name)
+			}
+		}
+
+		ch.group(types.ArrayUpdateOperationRemove, g)
+		ch.line("- %s group %s", groupKind(g), name)
+		deletedGroup[name] = true
+	}
+
+	// A group deleted in this run is no longer there to update or refer to
+
+	for i := 0; i < len(config.Group); i++ {
+		if deletedGroup[config.Group[i].GetClusterGroupInfo().Name] {
+			config.Group = append(config.Group[:i], config.Group[i+1:]...)
+			i--
+		}
+	}
+	for i := 0; i < len(config.Rule); i++ {
+		if deletedRule[config.Rule[i].GetClusterRuleInfo().Name] {
+			config.Rule = append(config.Rule[:i], config.Rule[i+1:]...)
+			i--
+		}
+	}
+
+	if vmGroup != "" {
+		ch.setGroup(config, &types.ClusterVmGroup{ClusterGroupInfo: types.ClusterGroupInfo{Name: vmGroup}, Vm: vms}, names)
+	}
+
+	if hostGroup != "" {
+		ch.setGroup(config, &types.ClusterHostGroup{ClusterGroupInfo: types.ClusterGroupInfo{Name: hostGroup}, Host: hosts}, names)
+	}
+
+	if ruleName != "" {
+		info := types.ClusterRuleInfo{Name: ruleName, Enabled: types.NewBool(enabled)}
+
+		switch ruleType {
+		case "affinity", "anti-affinity":
+			if len(vms) < 2 {
+				ch.problem("a VM-VM rule needs at least two VMs (-vm / -node)")
+				break
+			}
+			if ruleType == "affinity" {
+				ch.setRule(config, &types.ClusterAffinityRuleSpec{ClusterRuleInfo: info, Vm: vms}, names)
+			} else {
+				ch.setRule(config, &types.ClusterAntiAffinityRuleSpec{ClusterRuleInfo: info, Vm: vms}, names)
+			}
+		case "vm-host":
+			if useVMGroup == "" {
+				useVMGroup = vmGroup
+			}
+			if useHostGroup == "" {
+				useHostGroup = hostGroup
+			}
+
+			// The groups have to exist, or be created in this run
+
+			exists := func(name, kind, set string) bool {
+				if g := findGroup(config, name); g != nil {
+					return groupKind(g) == kind
+				}
+				return name == set
+			}
+			if !exists(useVMGroup, "VM", vmGroup) {
+				ch.problem("a vm-host rule needs a VM group (-vm-group or -set-vm-group), %q is not one", useVMGroup)
+				break
+			}
+			if !exists(useHostGroup, "Host", hostGroup) {
+				ch.problem("a vm-host rule needs a host group (-host-group or -set-host-group), %q is not one", useHostGroup)
+				break
+			}
+
+			p := policies[policy]
+			info.Mandatory = types.NewBool(p.mandatory)
+			rule := &types.ClusterVmHostRuleInfo{ClusterRuleInfo: info, VmGroupName: useVMGroup}
+			if p.affine {
+				rule.AffineHostGroupName = useHostGroup
+			} else {
+				rule.AntiAffineHostGroupName = useHostGroup
+			}
+			ch.setRule(config, rule, names)
+		default:
+			ch.problem("-rule-type must be vm-host, affinity or anti-affinity")
+		}
+	}
+
+	//
+	// Print the plan, then make the one reconfigure call
+	//
+
+	fmt.Printf("\n%s\n", cluster.Name)
+
+	for _, l := range ch.Lines {
+		fmt.Printf("%s\n", l)
+	}
+	for _, p := range ch.Problems {
+		fmt.Printf("! %s\n", p)
+	}
+
+	if len(ch.Problems) > 0 {
+		fmt.Printf("\nNot reconfiguring %s\n", cluster.Name)
+		os.Exit(1)
+	}
+
+	if len(ch.Spec.GroupSpec) == 0 && len(ch.Spec.RulesSpec) == 0 {
+		fmt.Printf("\nNothing to change\n")
+		return
+	}
+
+	if !apply {
+		fmt.Printf("\nDry run - re-run with -apply to make these changes\n")
+		return
+	}
+
+	task, err := object.NewClusterComputeResource(c, ref).Reconfigure(ctx, &ch.Spec, true)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil {
+		fmt.Printf("\nReconfigure failed, error %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nReconfigured %s\n", cluster.Name)
+}
@@ -0,0 +1,599 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and put an ESXi host into (or take it out of) maintenance mode, draining the
+//			Kubernetes nodes running on it first
+//
+//			  -host esx-01 -op enter    cordon the Kubernetes nodes on the host, evict their pods (honouring
+//			                            PodDisruptionBudgets) and enter maintenance mode
+//			  -host esx-01 -op exit     exit maintenance mode and uncordon the nodes cordoned by -op enter
+//
+//			Nodes are matched to the VMs on the host as get-k8s-nodes does - by the vsphere://<BIOS UUID>
+//			providerID, or else by name. DaemonSet and static pods are left alone; pods without a controller
+//			need -force and pods with emptyDir volumes -delete-emptydir, as with kubectl drain. The node VMs
+//			keep running - DRS moves them off the host with the other VMs. Each cordoned node is annotated
+//			with the host, so -op exit only uncordons the nodes it cordoned, wherever they run by then.
+//			Without -apply the plan is only printed.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	"k8s.io/client-go/util/retry"
+)
+
+// hostAnnotation is set on the nodes cordoned for a host's maintenance, with the host name as its value
+const hostAnnotation = "govmomi-snippets/maintenance-host"
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+//
+// findObject resolves an inventory object of the given type by inventory path (starts with "/") or by name
+//
+
+func findObject(ctx context.Context, c *vim25.Client, kind, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		elements, err := find.NewFinder(c).ManagedObjectList(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		for _, e := range elements {
+			if e.Object.Reference().Type == kind {
+				return e.Object.Reference(), nil
+			}
+		}
+		return none, fmt.Errorf("no %s at %s", kind, arg)
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{kind}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{kind}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no %s named %s", kind, arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d objects of type %s are named %s, use the path instead:\n  %s", len(refs), kind, arg, strings.Join(paths, "\n  "))
+}
+
+//
+// hostNodes picks the Kubernetes nodes whose VM runs on the host - by the BIOS UUID in the providerID, or by
+// a VM with the same name as the node
+//
+
+func hostNodes(nodes []corev1.Node, vms []mo.VirtualMachine) []corev1.Node {
+	uuids := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, vm := range vms {
+		if vm.Config != nil {
+			uuids[strings.ToLower(vm.Config.Uuid)] = true
+		}
+		names[vm.Name] = true
+	}
+
+	var found []corev1.Node
+	for _, node := range nodes {
+		if uuid := strings.TrimPrefix(node.Spec.ProviderID, "vsphere://"); uuid != node.Spec.ProviderID {
+			if uuids[strings.ToLower(uuid)] {
+				found = append(found, node)
+			}
+			continue
+		}
+		if names[node.Name] {
+			found = append(found, node)
+		}
+	}
+	return found
+}
+
+//
+// Drain is the eviction plan for one node - the pods to evict, the ones left alone and why, and the pods that
+// stop the drain unless -force / -delete-emptydir is given
+//
+
+type Drain struct {
+	Node     corev1.Node
+	Evict    []corev1.Pod
+	Skip     []string
+	Problems []string
+}
+
+func planDrain(node corev1.Node, pods []corev1.Pod, force, deleteEmptyDir bool) Drain {
+	d := Drain{Node: node}
+
+	for _, pod := range pods {
+		name := pod.Namespace + "/" + pod.Name
+
+		if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+			d.Skip = append(d.Skip, name+" (static pod)")
+			continue
+		}
+
+		owner := v1.GetControllerOf(&pod)
+		if owner != nil && owner.Kind == "DaemonSet" {
+			d.Skip = append(d.Skip, name+" (DaemonSet)")
+			continue
+		}
+
+		// Pods that have finished can always go
+
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			if owner == nil && !force {
+				d.Problems = append(d.Problems, fmt.Sprintf("%s is not managed by a controller and would be lost (-force)", name))
+				continue
+			}
+
+			emptyDir := ""
+			for _, vol := range pod.Spec.Volumes {
+				if vol.EmptyDir != nil {
+					emptyDir = vol.Name
+					break
+				}
+			}
+			if emptyDir != "" && !deleteEmptyDir {
+				d.Problems = append(d.Problems, fmt.Sprintf("%s has emptyDir volume %s, whose data would be lost (-delete-emptydir)", name, emptyDir))
+				continue
+			}
+		}
+
+		d.Evict = append(d.Evict, pod)
+	}
+
+	return d
+}
+
+//
+// evict asks the API server to evict a pod, which honours PodDisruptionBudgets - while a budget does not allow
+// the disruption the request is refused with 429, and is retried until it goes through or ctx runs out.
+// It then waits for the pod to be gone.
+//
+
+func evict(ctx context.Context, cs kubernetes.Interface, pod corev1.Pod) error {
+	eviction := &policyv1beta1.Eviction{
+		ObjectMeta: v1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	}
+
+	for {
+		err := cs.CoreV1().Pods(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("PodDisruptionBudget still does not allow eviction: %v", err)
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	for {
+		p, err := cs.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, v1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && p.UID != pod.UID) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("pod did not terminate: %v", ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// drain evicts the pods of a node in parallel, waiting up to timeout for them all to go
+func drain(ctx context.Context, cs kubernetes.Interface, d Drain, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+
+	for _, pod := range d.Evict {
+		wg.Add(1)
+
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+
+			err := evict(ctx, cs, pod)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Printf("! %s: evicting %s/%s failed, error %v\n", d.Node.Name, pod.Namespace, pod.Name, err)
+				failed++
+				return
+			}
+			fmt.Printf("  %s: evicted %s/%s\n", d.Node.Name, pod.Namespace, pod.Name)
+		}(pod)
+	}
+
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%d pod(s) could not be evicted", failed)
+	}
+	return nil
+}
+
+//
+// cordon marks a node unschedulable and notes the host it was cordoned for - or, with cordon false, undoes both
+//
+
+func cordon(ctx context.Context, cs kubernetes.Interface, name, host string, cordon bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := cs.CoreV1().Nodes().Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		node.Spec.Unschedulable = cordon
+		if cordon {
+			if node.Annotations == nil {
+				node.Annotations = make(map[string]string)
+			}
+			node.Annotations[hostAnnotation] = host
+		} else {
+			delete(node.Annotations, hostAnnotation)
+		}
+
+		_, err = cs.CoreV1().Nodes().Update(ctx, node, v1.UpdateOptions{})
+		return err
+	})
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var hostArg, op, vsanMode string
+	var force, deleteEmptyDir, evacuateOff, apply bool
+	var timeout, drainTimeout time.Duration
+
+	var kubeconfig *string
+	if home := homedir.HomeDir(); home != "" {
+		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+	} else {
+		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	}
+
+	flag.StringVar(&hostArg, "host", "", "ESXi host name or inventory path")
+	flag.StringVar(&op, "op", "enter", "enter or exit maintenance mode")
+	flag.BoolVar(&force, "force", false, "evict pods that are not managed by a controller")
+	flag.BoolVar(&deleteEmptyDir, "delete-emptydir", false, "evict pods with emptyDir volumes, losing their data")
+	flag.DurationVar(&drainTimeout, "drain-timeout", 10*time.Minute, "how long to wait for the pods of a node to be evicted")
+	flag.DurationVar(&timeout, "timeout", 30*time.Minute, "how long to wait for the host to enter or exit maintenance mode")
+	flag.BoolVar(&evacuateOff, "evacuate-powered-off", false, "move powered off VMs off the host too")
+	flag.StringVar(&vsanMode, "vsan-mode", "", "vSAN data migration: ensureObjectAccessibility, evacuateAllData or noAction")
+	flag.BoolVar(&apply, "apply", false, "carry out the plan rather than only printing it")
+	flag.Parse()
+
+	if hostArg == "" || (op != "enter" && op != "exit") {
+		fmt.Printf("usage: set-host-maintenance -host name|path [-op enter|exit] [-apply]\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	ref, err := findObject(ctx, c, "HostSystem", hostArg)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+
+	pc := property.DefaultCollector(c)
+
+	var host mo.HostSystem
+	if err = pc.RetrieveOne(ctx, ref, []string{"name", "runtime", "vm", "parent"}, &host); err != nil {
+		fmt.Printf("Could not retrieve host %s, error %v\n", hostArg, err)
+		return
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		fmt.Printf("Could not read kubeconfig %s, error %v\n", *kubeconfig, err)
+		return
+	}
+
+	cs, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Printf("Could not create Kubernetes client, error %v\n", err)
+		return
+	}
+
+	nodeList, err := cs.CoreV1().Nodes().List(ctx, v1.ListOptions{})
+	if err != nil {
+		fmt.Printf("Could not list Kubernetes nodes, error %v\n", err)
+		return
+	}
+
+	hs := object.NewHostSystem(c, ref)
+	seconds := int32(timeout.Seconds())
+
+	if !apply {
+		fmt.Printf("Dry run - re-run with -apply to make these changes\n")
+	}
+	fmt.Printf("\n%s (%s)\n", host.Name, map[bool]string{true: "in maintenance mode", false: "not in maintenance mode"}[host.Runtime.InMaintenanceMode])
+
+	//
+	// Exit - leave maintenance mode, then uncordon the nodes that were cordoned for this host
+	//
+
+	if op == "exit" {
+		var uncordon []string
+		for _, node := range nodeList.Items {
+			if node.Annotations[hostAnnotation] == host.Name {
+				uncordon = append(uncordon, node.Name)
+			}
+		}
+		sort.Strings(uncordon)
+
+		if host.Runtime.InMaintenanceMode {
+			fmt.Printf("~ exit maintenance mode\n")
+		} else {
+			fmt.Printf("= not in maintenance mode\n")
+		}
+		for _, name := range uncordon {
+			fmt.Printf("~ uncordon node %s\n", name)
+		}
+
+		if !apply {
+			return
+		}
+
+		fmt.Println()
+
+		if host.Runtime.InMaintenanceMode {
+			task, err := hs.ExitMaintenanceMode(ctx, seconds)
+			if err == nil {
+				err = task.Wait(ctx)
+			}
+			if err != nil {
+				fmt.Printf("Could not exit maintenance mode, error %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("  %s is out of maintenance mode\n", host.Name)
+		}
+
+		for _, name := range uncordon {
+			if err = cordon(ctx, cs, name, host.Name, false); err != nil {
+				fmt.Printf("! could not uncordon %s, error %v\n", name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("  uncordoned %s\n", name)
+		}
+		return
+	}
+
+	//
+	// Enter - find the nodes on the host and plan their drain
+	//
+
+	if host.Runtime.InMaintenanceMode {
+		fmt.Printf("= already in maintenance mode\n")
+		return
+	}
+
+	var vms []mo.VirtualMachine
+	if len(host.Vm) > 0 {
+		if err = pc.Retrieve(ctx, host.Vm, []string{"name", "config.uuid", "runtime.powerState"}, &vms); err != nil {
+			fmt.Printf("Could not retrieve the VMs on %s, error %v\n", host.Name, err)
+			return
+		}
+	}
+
+	nodes := hostNodes(nodeList.Items, vms)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	var drains []Drain
+	problems := 0
+
+	for _, node := range nodes {
+		pods, err := cs.CoreV1().Pods("").List(ctx, v1.ListOptions{FieldSelector: "spec.nodeName=" + node.Name})
+		if err != nil {
+			fmt.Printf("Could not list the pods on %s, error %v\n", node.Name, err)
+			return
+		}
+
+		d := planDrain(node, pods.Items, force, deleteEmptyDir)
+		drains = append(drains, d)
+
+		switch {
+		case !node.Spec.Unschedulable:
+			fmt.Printf("~ cordon node %s\n", node.Name)
+		case node.Annotations[hostAnnotation] == host.Name:
+			fmt.Printf("= node %s already cordoned\n", node.Name)
+		default:
+			fmt.Printf("= node %s already cordoned (not by this tool, it will be left cordoned on exit)\n", node.Name)
+		}
+		for _, pod := range d.Evict {
+			fmt.Printf("- evict %s/%s\n", pod.Namespace, pod.Name)
+		}
+		for _, s := range d.Skip {
+			fmt.Printf("= leave %s\n", s)
+		}
+		for _, p := range d.Problems {
+			fmt.Printf("! %s\n", p)
+		}
+		problems += len(d.Problems)
+	}
+
+	if len(nodes) == 0 {
+		fmt.Printf("= no Kubernetes nodes on this host\n")
+	}
+
+	var other []string
+	for _, vm := range vms {
+		if vm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn || evacuateOff {
+			other = append(other, vm.Name)
+		}
+	}
+	sort.Strings(other)
+
+	fmt.Printf("~ enter maintenance mode, moving %d VM(s) off the host\n", len(other))
+
+	// Without fully automated DRS the VMs have to be moved by hand while the task waits
+
+	if host.Parent != nil && host.Parent.Type == "ClusterComputeResource" && len(other) > 0 {
+		var cluster mo.ClusterComputeResource
+		if err = pc.RetrieveOne(ctx, *host.Parent, []string{"configurationEx"}, &cluster); err == nil {
+			if cfg, ok := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+				drs := cfg.DrsConfig
+				if drs.Enabled == nil || !*drs.Enabled || drs.DefaultVmBehavior != types.DrsBehaviorFullyAutomated {
+					fmt.Printf("! DRS is not fully automated - %s must be moved off by hand\n", strings.Join(other, ", "))
+				}
+			}
+		}
+	}
+
+	if problems > 0 {
+		fmt.Printf("\nNot entering maintenance mode\n")
+		os.Exit(1)
+	}
+
+	if !apply {
+		return
+	}
+
+	//
+	// Cordon every node first, so pods evicted from one node are not scheduled onto another node on this host
+	//
+
+	fmt.Println()
+
+	for _, d := range drains {
+		if d.Node.Spec.Unschedulable {
+			continue
+		}
+		if err = cordon(ctx, cs, d.Node.Name, host.Name, true); err != nil {
+			fmt.Printf("! could not cordon %s, error %v\n", d.Node.Name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("  cordoned %s\n", d.Node.Name)
+	}
+
+	for _, d := range drains {
+		if err = drain(ctx, cs, d, drainTimeout); err != nil {
+			fmt.Printf("! could not drain %s, error %v\n", d.Node.Name, err)
+			fmt.Printf("\nNot entering maintenance mode - the nodes stay cordoned, use -op exit to uncordon them\n")
+			os.Exit(1)
+		}
+	}
+
+	var spec *types.HostMaintenanceSpec
+	if vsanMode != "" {
+		spec = &types.HostMaintenanceSpec{VsanMode: &types.VsanHostDecommissionMode{ObjectAction: vsanMode}}
+	}
+
+	task, err := hs.EnterMaintenanceMode(ctx, seconds, evacuateOff, spec)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil {
+		fmt.Printf("\nCould not enter maintenance mode, error %v\n", err)
+		fmt.Printf("The nodes stay cordoned, use -op exit to uncordon them\n")
+		os.Exit(1)
+	}
+
+	fmt.Printf("  %s is in maintenance mode\n", host.Name)
+}
@@ -0,0 +1,570 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and manage tag categories, tags and tag attachments from a manifest
+//
+//			The manifest is YAML (or JSON) or CSV. Every entry is a desired state, so the same manifest
+//			can be re-run - anything already in place is left alone. Without -apply the plan is only printed.
+//
+//			YAML:
+//
+//			  categories:
+//			  - name: env
+//			    cardinality: SINGLE
+//			    associableTypes: [VirtualMachine, HostSystem]
+//			  tags:
+//			  - name: prod
+//			    category: env
+//			  attachments:
+//			  - tag: env:prod
+//			    objects: [/DC0/vm/web-*, /DC0/host/Cluster0]
+//			  - tag: env:dev
+//			    objects: [/DC0/vm/old-vm]
+//			    state: absent
+//
+//			CSV (header required, columns in any order, associableTypes separated by ";"):
+//
+//			  kind,category,tag,description,cardinality,associableTypes,object,state
+//			  category,env,,,SINGLE,VirtualMachine;HostSystem,,
+//			  tag,env,prod,,,,,
+//			  attach,env,prod,,,,/DC0/vm/web-*,
+//
+//			An empty description or cardinality leaves the live value alone. Objects are absolute
+//			inventory paths and may contain wildcards. state is "present"
+//			(the default) or "absent", which deletes the category/tag or detaches the tag.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest is the desired state of categories, tags and attachments
+type Manifest struct {
+	Categories  []CategorySpec   `json:"categories,omitempty"`
+	Tags        []TagSpec        `json:"tags,omitempty"`
+	Attachments []AttachmentSpec `json:"attachments,omitempty"`
+}
+
+// CategorySpec describes a tag category
+type CategorySpec struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description,omitempty"`
+	Cardinality     string   `json:"cardinality,omitempty"`
+	AssociableTypes []string `json:"associableTypes,omitempty"`
+	State           string   `json:"state,omitempty"`
+}
+
+// TagSpec describes a tag within a category
+type TagSpec struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state,omitempty"`
+}
+
+// AttachmentSpec attaches (or detaches) a "category:tag" to the objects at the given inventory paths
+type AttachmentSpec struct {
+	Tag     string   `json:"tag"`
+	Objects []string `json:"objects"`
+	State   string   `json:"state,omitempty"`
+}
+
+//
+// vlogin returns both clients needed here - the vim25 client to find objects by inventory path,
+// and the rest client (which shares the vim25 session) for tags
+//
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, *rest.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in (vim25) not successful- could not get vCenter client: %v\n", err)
+		return nil, nil, err
+	}
+
+	rc := rest.NewClient(c)
+
+	err = s.Login(ctx, rc, nil)
+	if err != nil {
+		fmt.Printf("Log in (rest) not successful- could not get vCenter client: %v\n", err)
+		return nil, nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, rc, nil
+}
+
+//
+// readManifest picks the format from the file extension - .csv is CSV, anything else is YAML (or JSON)
+//
+
+func readManifest(path string) (Manifest, error) {
+	var manifest Manifest
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) != ".csv" {
+		err = yaml.UnmarshalStrict(data, &manifest)
+		return manifest, err
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		return manifest, err
+	}
+	if len(records) == 0 {
+		return manifest, nil
+	}
+
+	column := make(map[string]int)
+	for i, name := range records[0] {
+		column[strings.TrimSpace(name)] = i
+	}
+	if _, ok := column["kind"]; !ok {
+		return manifest, fmt.Errorf("CSV header has no \"kind\" column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := column[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for n, record := range records[1:] {
+		switch field(record, "kind") {
+		case "category":
+			var associable []string
+			if t := field(record, "associableTypes"); t != "" {
+				associable = strings.Split(t, ";")
+			}
+			manifest.Categories = append(manifest.Categories, CategorySpec{
+				Name:            field(record, "category"),
+				Description:     field(record, "description"),
+				Cardinality:     field(record, "cardinality"),
+				AssociableTypes: associable,
+				State:           field(record, "state"),
+			})
+		case "tag":
+			manifest.Tags = append(manifest.Tags, TagSpec{
+				Name:        field(record, "tag"),
+				Category:    field(record, "category"),
+				Description: field(record, "description"),
+				State:       field(record, "state"),
+			})
+		case "attach":
+			manifest.Attachments = append(manifest.Attachments, AttachmentSpec{
+				Tag:     field(record, "category") + ":" + field(record, "tag"),
+				Objects: []string{field(record, "object")},
+				State:   field(record, "state"),
+			})
+		default:
+			return manifest, fmt.Errorf("line %d: unknown kind %q (want category, tag or attach)", n+2, field(record, "kind"))
+		}
+	}
+
+	return manifest, nil
+}
+
+//
+// absent reports whether an entry asks for removal - an empty state means "present"
+//
+
+func absent(state string) (bool, error) {
+	switch state {
+	case "", "present":
+		return false, nil
+	case "absent":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown state %q (want present or absent)", state)
+}
+
+//
+// sameTypes compares associable types ignoring order
+//
+
+func sameTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string{}, a...)
+	y := append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//
+// reconcile walks the manifest in dependency order - categories, tags, attachments - and leaves the deletes
+// until the end, so a tag can be detached before it (or its category) is removed
+//
+
+func reconcile(ctx context.Context, c *vim25.Client, m *tags.Manager, manifest Manifest, apply bool) error {
+	categories, err := m.GetCategories(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get list of categories: %s", err)
+	}
+
+	categoryByName := make(map[string]tags.Category)
+	for _, cat := range categories {
+		categoryByName[cat.Name] = cat
+	}
+
+	tagList, err := m.GetTags(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get list of tags: %s", err)
+	}
+
+	// Tag names are only unique within a category, so key them by "category:tag"
+
+	categoryNames := make(map[string]string)
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+
+	tagByName := make(map[string]tags.Tag)
+	for _, tag := range tagList {
+		tagByName[categoryNames[tag.CategoryID]+":"+tag.Name] = tag
+	}
+
+	var deleteTags []tags.Tag
+	var deleteCategories []tags.Category
+
+	//
+	// -- Categories
+	//
+
+	for _, want := range manifest.Categories {
+		remove, err := absent(want.State)
+		if err != nil {
+			return fmt.Errorf("category %s: %s", want.Name, err)
+		}
+
+		live, exists := categoryByName[want.Name]
+
+		switch {
+		case remove && !exists:
+			fmt.Printf("= category %s (absent)\n", want.Name)
+
+		case remove:
+			fmt.Printf("- category %s (and its tags)\n", want.Name)
+			deleteCategories = append(deleteCategories, live)
+
+		case !exists:
+			cardinality := want.Cardinality
+			if cardinality == "" {
+				cardinality = "SINGLE"
+			}
+			associable := "all objects"
+			if len(want.AssociableTypes) > 0 {
+				associable = strings.Join(want.AssociableTypes, ", ")
+			}
+			fmt.Printf("+ category %s (%s, %s)\n", want.Name, cardinality, associable)
+
+			cat := tags.Category{
+				Name:            want.Name,
+				Description:     want.Description,
+				Cardinality:     cardinality,
+				AssociableTypes: want.AssociableTypes,
+			}
+			if apply {
+				if cat.ID, err = m.CreateCategory(ctx, &cat); err != nil {
+					return fmt.Errorf("could not create category %s: %s", want.Name, err)
+				}
+			}
+			categoryByName[want.Name] = cat
+
+		default:
+			var changes []string
+			update := live
+
+			if want.Description != "" && want.Description != live.Description {
+				changes = append(changes, fmt.Sprintf("description: %q -> %q", live.Description, want.Description))
+				update.Description = want.Description
+			}
+			if want.Cardinality != "" && want.Cardinality != live.Cardinality {
+				changes = append(changes, fmt.Sprintf("cardinality: %s -> %s", live.Cardinality, want.Cardinality))
+				update.Cardinality = want.Cardinality
+			}
+			if want.AssociableTypes != nil && !sameTypes(want.AssociableTypes, live.AssociableTypes) {
+				changes = append(changes, fmt.Sprintf("associableTypes: %v -> %v", live.AssociableTypes, want.AssociableTypes))
+				update.AssociableTypes = want.AssociableTypes
+			}
+
+			if len(changes) == 0 {
+				fmt.Printf("= category %s\n", want.Name)
+				continue
+			}
+
+			fmt.Printf("~ category %s\n", want.Name)
+			for _, change := range changes {
+				fmt.Printf("    %s\n", change)
+			}
+
+			// vCenter only allows associable types to be added and cardinality to go from SINGLE to MULTIPLE
+
+			if apply {
+				if err = m.UpdateCategory(ctx, &update); err != nil {
+					return fmt.Errorf("could not update category %s: %s", want.Name, err)
+				}
+			}
+		}
+	}
+
+	//
+	// -- Tags
+	//
+
+	for _, want := range manifest.Tags {
+		key := want.Category + ":" + want.Name
+
+		remove, err := absent(want.State)
+		if err != nil {
+			return fmt.Errorf("tag %s: %s", key, err)
+		}
+
+		live, exists := tagByName[key]
+
+		switch {
+		case remove && !exists:
+			fmt.Printf("= tag %s (absent)\n", key)
+
+		case remove:
+			fmt.Printf("- tag %s\n", key)
+			deleteTags = append(deleteTags, live)
+
+		case !exists:
+			cat, ok := categoryByName[want.Category]
+			if !ok {
+				return fmt.Errorf("tag %s: category %s does not exist and is not in the manifest", key, want.Category)
+			}
+
+			fmt.Printf("+ tag %s\n", key)
+
+			tag := tags.Tag{
+				Name:        want.Name,
+				Description: want.Description,
+				CategoryID:  cat.ID,
+			}
+			if apply {
+				if tag.ID, err = m.CreateTag(ctx, &tag); err != nil {
+					return fmt.Errorf("could not create tag %s: %s", key, err)
+				}
+			}
+			tagByName[key] = tag
+
+		case want.Description != "" && want.Description != live.Description:
+			fmt.Printf("~ tag %s\n", key)
+			fmt.Printf("    description: %q -> %q\n", live.Description, want.Description)
+
+			live.Description = want.Description
+			if apply {
+				if err = m.UpdateTag(ctx, &live); err != nil {
+					return fmt.Errorf("could not update tag %s: %s", key, err)
+				}
+			}
+
+		default:
+			fmt.Printf("= tag %s\n", key)
+		}
+	}
+
+	//
+	// -- Attachments
+	//
+
+	finder := find.NewFinder(c)
+
+	for _, want := range manifest.Attachments {
+		remove, err := absent(want.State)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %s", want.Tag, err)
+		}
+
+		tag, ok := tagByName[want.Tag]
+		if !ok {
+			if remove {
+				fmt.Printf("= tag %s does not exist, nothing to detach\n", want.Tag)
+				continue
+			}
+			return fmt.Errorf("attachment %s: tag does not exist and is not in the manifest (use category:tag)", want.Tag)
+		}
+
+		// Tags created in this run (or only planned) have nothing attached yet
+
+		attached := make(map[types.ManagedObjectReference]bool)
+		if tag.ID != "" {
+			refs, err := m.ListAttachedObjects(ctx, tag.ID)
+			if err != nil {
+				return fmt.Errorf("could not list objects attached to %s: %s", want.Tag, err)
+			}
+			for _, ref := range refs {
+				attached[ref.Reference()] = true
+			}
+		}
+
+		var attach []mo.Reference
+
+		for _, path := range want.Objects {
+			elements, err := finder.ManagedObjectList(ctx, path)
+			if err != nil || len(elements) == 0 {
+				fmt.Printf("! %s: no objects found at %s\n", want.Tag, path)
+				continue
+			}
+
+			for _, e := range elements {
+				ref := e.Object.Reference()
+
+				switch {
+				case remove && attached[ref]:
+					fmt.Printf("- %s from %s\n", want.Tag, e.Path)
+					if apply {
+						if err = m.DetachTag(ctx, tag.ID, ref); err != nil {
+							return fmt.Errorf("could not detach %s from %s: %s", want.Tag, e.Path, err)
+						}
+					}
+					delete(attached, ref)
+
+				case !remove && !attached[ref]:
+					fmt.Printf("+ %s on %s\n", want.Tag, e.Path)
+					attach = append(attach, ref)
+					attached[ref] = true
+
+				default:
+					fmt.Printf("= %s %s\n", want.Tag, e.Path)
+				}
+			}
+		}
+
+		if apply && len(attach) > 0 {
+			if err = m.AttachTagToMultipleObjects(ctx, tag.ID, attach); err != nil {
+				return fmt.Errorf("could not attach %s: %s", want.Tag, err)
+			}
+		}
+	}
+
+	//
+	// -- Deletes, tags before categories
+	//
+
+	if apply {
+		for i := range deleteTags {
+			if err = m.DeleteTag(ctx, &deleteTags[i]); err != nil {
+				return fmt.Errorf("could not delete tag %s: %s", deleteTags[i].Name, err)
+			}
+		}
+		for i := range deleteCategories {
+			if err = m.DeleteCategory(ctx, &deleteCategories[i]); err != nil {
+				return fmt.Errorf("could not delete category %s: %s", deleteCategories[i].Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var manifestPath string
+	var apply bool
+	flag.StringVar(&manifestPath, "f", "", "manifest of categories, tags and attachments (.yaml, .json or .csv)")
+	flag.BoolVar(&apply, "apply", false, "make the changes (default is to only print the plan)")
+	flag.Parse()
+
+	if manifestPath == "" {
+		fmt.Printf("usage: set-tags -f manifest.yaml [-apply]\n")
+		return
+	}
+
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		fmt.Printf("Could not read %s, error %v\n", manifestPath, err)
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, rc, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	m := tags.NewManager(rc)
+
+	if err = reconcile(ctx, c, m, manifest, apply); err != nil {
+		fmt.Printf("Error : %v\n", err)
+		return
+	}
+
+	if !apply {
+		fmt.Printf("\nNo changes made, re-run with -apply to carry out the plan above\n")
+	}
+}
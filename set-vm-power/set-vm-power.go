@@ -0,0 +1,527 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and change the power state of a selection of VMs
+//
+//			  -op on|off|reset|suspend|shutdown|reboot    shutdown and reboot go through VMware Tools
+//
+//			VMs are picked with any combination of -name (pattern), -folder (inventory path), -tag
+//			category:name and -node (a Kubernetes node in the current kubeconfig context) - a VM has
+//			to match all of them. Without -apply the VMs and what would happen to them are only
+//			listed. With -apply the operations run in parallel (-workers) and the progress of each
+//			vSphere task is printed as it runs.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/progress"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//
+// tagFilter holds the -tag category:name values. With -tag-match all (the default) an object must carry every
+// tag, with -tag-match any it needs at least one of them.
+//
+
+type tagFilter struct {
+	tags    []string
+	match   string
+	objects map[types.ManagedObjectReference]bool
+}
+
+func (f *tagFilter) String() string { return strings.Join(f.tags, ",") }
+
+func (f *tagFilter) Set(value string) error {
+	if i := strings.Index(value, ":"); i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("want category:name, got %q", value)
+	}
+	f.tags = append(f.tags, value)
+	return nil
+}
+
+//
+// resolve finds the objects that pass the filter. Tags live behind the vAPI (REST) endpoint, which needs its own
+// login, and GetAttachedObjectsOnTags returns the objects for all of the tags in one call.
+//
+
+func (f *tagFilter) resolve(ctx context.Context, c *vim25.Client, user *url.Userinfo) error {
+	if len(f.tags) == 0 {
+		return nil
+	}
+	if f.match != "all" && f.match != "any" {
+		return fmt.Errorf("-tag-match must be \"all\" or \"any\", not %q", f.match)
+	}
+
+	rc := rest.NewClient(c)
+	if err := rc.Login(ctx, user); err != nil {
+		return fmt.Errorf("could not log in to the tagging service: %s", err)
+	}
+	defer rc.Logout(ctx)
+
+	m := tags.NewManager(rc)
+
+	var ids []string
+	for _, name := range f.tags {
+		i := strings.Index(name, ":")
+		tag, err := m.GetTagForCategory(ctx, name[i+1:], name[:i])
+		if err != nil {
+			return fmt.Errorf("could not find tag %s: %s", name, err)
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	attached, err := m.GetAttachedObjectsOnTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("could not get the objects tagged %s: %s", f, err)
+	}
+
+	count := make(map[types.ManagedObjectReference]int)
+	for _, a := range attached {
+		for _, obj := range a.ObjectIDs {
+			count[obj.Reference()]++
+		}
+	}
+
+	f.objects = make(map[types.ManagedObjectReference]bool)
+	for ref, n := range count {
+		if f.match == "any" || n == len(ids) {
+			f.objects[ref] = true
+		}
+	}
+
+	return nil
+}
+
+// keep reports whether an object passes the filter - everything passes when no -tag was given
+func (f *tagFilter) keep(ref types.ManagedObjectReference) bool {
+	return len(f.tags) == 0 || f.objects[ref]
+}
+
+//
+// nodeVMs returns the VMs behind the -node arguments as a set, to be combined with the other VM selectors
+//
+
+func nodeVMs(ctx context.Context, c *vim25.Client, kubeconfig string, names []string) (map[types.ManagedObjectReference]bool, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	finder := find.NewFinder(c)
+	index := object.NewSearchIndex(c)
+	refs := make(map[types.ManagedObjectReference]bool)
+
+	for _, name := range names {
+		node, err := clientSet.CoreV1().Nodes().Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get node %s: %s", name, err)
+		}
+
+		if uuid := strings.TrimPrefix(node.Spec.ProviderID, "vsphere://"); uuid != node.Spec.ProviderID {
+			ref, err := index.FindByUuid(ctx, nil, uuid, true, nil)
+			if err != nil {
+				return nil, err
+			}
+			if ref != nil {
+				refs[ref.Reference()] = true
+				continue
+			}
+		}
+
+		vms, err := finder.VirtualMachineList(ctx, "/*/vm/.../"+name)
+		if err != nil || len(vms) != 1 {
+			return nil, fmt.Errorf("could not find a VM for node %s", name)
+		}
+		refs[vms[0].Reference()] = true
+	}
+
+	return refs, nil
+}
+
+//
+// operation describes what a -op does - which power state it applies to, the state the VM ends up in, and
+// whether it needs VMware Tools
+//
+
+type operation struct {
+	from  types.VirtualMachinePowerState
+	to    types.VirtualMachinePowerState
+	tools bool
+}
+
+var operations = map[string]operation{
+	"on":       {from: "", to: types.VirtualMachinePowerStatePoweredOn},
+	"off":      {from: "", to: types.VirtualMachinePowerStatePoweredOff},
+	"reset":    {from: types.VirtualMachinePowerStatePoweredOn, to: types.VirtualMachinePowerStatePoweredOn},
+	"suspend":  {from: types.VirtualMachinePowerStatePoweredOn, to: types.VirtualMachinePowerStateSuspended},
+	"shutdown": {from: types.VirtualMachinePowerStatePoweredOn, to: types.VirtualMachinePowerStatePoweredOff, tools: true},
+	"reboot":   {from: types.VirtualMachinePowerStatePoweredOn, to: types.VirtualMachinePowerStatePoweredOn, tools: true},
+}
+
+// plan returns why an operation would be skipped for a VM, or "" when it applies
+func plan(op string, vm mo.VirtualMachine) string {
+	o := operations[op]
+	state := vm.Runtime.PowerState
+
+	if o.from == "" && state == o.to {
+		return fmt.Sprintf("already %s", state)
+	}
+	if o.from != "" && state != o.from {
+		return fmt.Sprintf("is %s", state)
+	}
+	if o.tools && (vm.Guest == nil || vm.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning)) {
+		return "VMware Tools not running"
+	}
+	return ""
+}
+
+//
+// progressLogger prints the completion percentage of a task each time it moves on by 10% or more
+//
+
+type progressLogger struct {
+	name string
+	ch   chan progress.Report
+	done chan struct{}
+}
+
+func newProgressLogger(name string) *progressLogger {
+	p := &progressLogger{name: name, ch: make(chan progress.Report), done: make(chan struct{})}
+
+	go func() {
+		defer close(p.done)
+		last := float32(0)
+		for r := range p.ch {
+			if r.Percentage() >= last+10 && r.Percentage() < 100 {
+				last = r.Percentage()
+				fmt.Printf("  %s: %.0f%%\n", p.name, last)
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *progressLogger) Sink() chan<- progress.Report { return p.ch }
+
+// Wait returns once the task has finished and its last report was printed
+func (p *progressLogger) Wait() { <-p.done }
+
+//
+// run carries out one operation. Guest shutdown and reboot are requests to VMware Tools rather than tasks, so a
+// shutdown waits for the VM to power off, up to timeout.
+//
+
+func run(ctx context.Context, vm *object.VirtualMachine, name, op string, timeout time.Duration) error {
+	var task *object.Task
+	var err error
+
+	switch op {
+	case "on":
+		task, err = vm.PowerOn(ctx)
+	case "off":
+		task, err = vm.PowerOff(ctx)
+	case "reset":
+		task, err = vm.Reset(ctx)
+	case "suspend":
+		task, err = vm.Suspend(ctx)
+	case "shutdown":
+		if err = vm.ShutdownGuest(ctx); err != nil {
+			return err
+		}
+		wctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err = vm.WaitForPowerState(wctx, types.VirtualMachinePowerStatePoweredOff); err != nil {
+			return fmt.Errorf("guest did not power off within %s", timeout)
+		}
+		return nil
+	case "reboot":
+		return vm.RebootGuest(ctx)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	p := newProgressLogger(name)
+	_, err = task.WaitForResult(ctx, p)
+	p.Wait()
+
+	return err
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var op, name, folder string
+	var nodes stringList
+	var filter tagFilter
+	var workers int
+	var timeout time.Duration
+	var apply bool
+
+	var ops []string
+	for o := range operations {
+		ops = append(ops, o)
+	}
+	sort.Strings(ops)
+
+	var kubeconfig *string
+	if home := homedir.HomeDir(); home != "" {
+		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+	} else {
+		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	}
+
+	flag.StringVar(&op, "op", "", "power operation: "+strings.Join(ops, ", "))
+	flag.StringVar(&name, "name", "", "only VMs whose name matches this pattern")
+	flag.StringVar(&folder, "folder", "", "only VMs in (or below) this inventory folder")
+	flag.Var(&filter, "tag", "only VMs carrying this tag, as category:name (repeatable)")
+	flag.StringVar(&filter.match, "tag-match", "all", "with several -tag, match \"all\" or \"any\" of them")
+	flag.Var(&nodes, "node", "the VM behind this Kubernetes node (repeatable)")
+	flag.IntVar(&workers, "workers", 4, "number of operations to run at once")
+	flag.DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for a guest shutdown")
+	flag.BoolVar(&apply, "apply", false, "carry out the operation rather than only listing the VMs")
+	flag.Parse()
+
+	if _, ok := operations[op]; !ok {
+		fmt.Printf("-op must be one of %s\n", strings.Join(ops, ", "))
+		return
+	}
+
+	// Never act on the whole inventory by accident - use -name '*' to mean every VM
+
+	if name == "" && folder == "" && len(filter.tags) == 0 && len(nodes) == 0 {
+		fmt.Printf("select the VMs with -name, -folder, -tag or -node\n")
+		return
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	if err = filter.resolve(ctx, c, url.UserPassword(user, pwd)); err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+
+	var nodeRefs map[types.ManagedObjectReference]bool
+	if len(nodes) > 0 {
+		if nodeRefs, err = nodeVMs(ctx, c, *kubeconfig, nodes); err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+	}
+
+	//
+	// The container view is rooted at -folder when one is given, so only the VMs below it are seen
+	//
+
+	root := c.ServiceContent.RootFolder
+	if folder != "" {
+		f, err := find.NewFinder(c).Folder(ctx, folder)
+		if err != nil {
+			fmt.Printf("Could not find folder %s, error %v\n", folder, err)
+			return
+		}
+		root = f.Reference()
+	}
+
+	m := view.NewManager(c)
+
+	v, err := m.CreateContainerView(ctx, root, []string{"VirtualMachine"}, true)
+	if err != nil {
+		fmt.Printf("Could not create container view, error %v\n", err)
+		return
+	}
+
+	defer v.Destroy(ctx)
+
+	pattern := name
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	refs, err := v.Find(ctx, []string{"VirtualMachine"}, property.Filter{"name": pattern})
+	if err != nil {
+		fmt.Printf("Could not find VMs, error %v\n", err)
+		return
+	}
+
+	var selected []types.ManagedObjectReference
+	for _, ref := range refs {
+		if filter.keep(ref) && (nodeRefs == nil || nodeRefs[ref]) {
+			selected = append(selected, ref)
+		}
+	}
+
+	if len(selected) == 0 {
+		fmt.Printf("No VMs match the selection\n")
+		return
+	}
+
+	var vms []mo.VirtualMachine
+	err = property.DefaultCollector(c).Retrieve(ctx, selected, []string{"name", "runtime.powerState", "guest.toolsRunningStatus"}, &vms)
+	if err != nil {
+		fmt.Printf("Could not retrieve VM power state, error %v\n", err)
+		return
+	}
+
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+
+	//
+	// Print the plan, then run the operations that apply
+	//
+
+	if !apply {
+		fmt.Printf("Dry run - re-run with -apply to make these changes\n")
+	}
+	fmt.Println()
+
+	var todo []mo.VirtualMachine
+	for _, vm := range vms {
+		if skip := plan(op, vm); skip != "" {
+			fmt.Printf("= %s %s\n", vm.Name, skip)
+			continue
+		}
+		fmt.Printf("~ %s %s -> %s\n", vm.Name, vm.Runtime.PowerState, op)
+		todo = append(todo, vm)
+	}
+
+	if !apply || len(todo) == 0 {
+		return
+	}
+
+	fmt.Println()
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+
+	for _, vm := range todo {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(vm mo.VirtualMachine) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := run(ctx, object.NewVirtualMachine(c, vm.Self), vm.Name, op, timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Printf("! %s %s failed, error %v\n", vm.Name, op, err)
+				failed++
+				return
+			}
+			fmt.Printf("  %s: %s done\n", vm.Name, op)
+		}(vm)
+	}
+
+	wg.Wait()
+
+	fmt.Printf("\n%d of %d VM(s) done\n", len(todo)-failed, len(todo))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
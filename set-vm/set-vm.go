@@ -0,0 +1,537 @@
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// Description:		Go code to connect to vSphere via environment
+//			variables and reconfigure a VM - vCPU, memory, reservations, disks and NICs
+//
+//			  -cpu 4 -cores 2 -mem 8GB                       size (hot added when the VM allows it)
+//			  -cpu-reservation 1000 -mem-reservation 4GB     reservations, in MHz and bytes
+//			  -hot-add on|off                                enable CPU/memory hot add (VM powered off)
+//			  -add-disk 20GB [-datastore ds] [-thin=false]   add a disk (repeatable)
+//			  -extend-disk "Hard disk 1=40GB"                grow a disk (repeatable)
+//			  -remove-disk "Hard disk 2" [-delete-files]     detach a disk, deleting its files if asked (repeatable)
+//			  -add-nic portgroup [-nic-type vmxnet3]         add a network adapter (repeatable)
+//			  -remove-nic "Network adapter 2"                remove a network adapter (repeatable)
+//
+//			All the changes are made in one Reconfigure call. Without -apply they are only printed.
+//
+// Author:		Cormac J. Hogan (VMware)
+//
+// Date:		18 Oct 2026
+//
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vlogin(ctx context.Context, vc, user, pwd string) (*vim25.Client, error) {
+
+	u, err := soap.ParseURL(vc)
+
+	if u == nil {
+		fmt.Printf("could not parse URL (environment variables set?)\n")
+	}
+
+	if err != nil {
+		fmt.Printf("URL parsing not successful, error %v\n", err)
+		return nil, err
+	}
+
+	u.User = url.UserPassword(user, pwd)
+
+	// Share session cache
+	s := &cache.Session{
+		URL:      u,
+		Insecure: true,
+	}
+
+	c := new(vim25.Client)
+
+	err = s.Login(ctx, c, nil)
+	if err != nil {
+		fmt.Printf("Log in not successful- could not get vCenter client: %v\n", err)
+		return nil, err
+	}
+
+	fmt.Printf("Log in successful\n")
+
+	return c, nil
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// isKind reports whether ref is of the given type - port groups and opaque networks count as networks
+func isKind(ref types.ManagedObjectReference, kind string) bool {
+	if kind == "Network" {
+		return ref.Type == "Network" || ref.Type == "DistributedVirtualPortgroup" || ref.Type == "OpaqueNetwork"
+	}
+	return ref.Type == kind
+}
+
+//
+// findObject resolves an inventory object of the given type by inventory path (starts with "/") or by name
+//
+
+func findObject(ctx context.Context, c *vim25.Client, kind, arg string) (types.ManagedObjectReference, error) {
+	var none types.ManagedObjectReference
+
+	if strings.HasPrefix(arg, "/") {
+		elements, err := find.NewFinder(c).ManagedObjectList(ctx, arg)
+		if err != nil {
+			return none, err
+		}
+		for _, e := range elements {
+			if isKind(e.Object.Reference(), kind) {
+				return e.Object.Reference(), nil
+			}
+		}
+		return none, fmt.Errorf("no %s at %s", kind, arg)
+	}
+
+	v, err := view.NewManager(c).CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{kind}, true)
+	if err != nil {
+		return none, err
+	}
+
+	defer v.Destroy(ctx)
+
+	refs, err := v.Find(ctx, []string{kind}, property.Filter{"name": arg})
+	if err != nil {
+		return none, err
+	}
+
+	switch len(refs) {
+	case 0:
+		return none, fmt.Errorf("no %s named %s", kind, arg)
+	case 1:
+		return refs[0], nil
+	}
+
+	var paths []string
+	for _, ref := range refs {
+		path, _ := find.InventoryPath(ctx, c, ref)
+		paths = append(paths, path)
+	}
+	return none, fmt.Errorf("%d objects of type %s are named %s, use the path instead:\n  %s", len(refs), kind, arg, strings.Join(paths, "\n  "))
+}
+
+// findDevice finds a device by its label ("Hard disk 2") or by its govc style name ("disk-1000-1")
+func findDevice(devices object.VirtualDeviceList, id string) types.BaseVirtualDevice {
+	for _, d := range devices {
+		if info := d.GetVirtualDevice().DeviceInfo; info != nil && strings.EqualFold(info.GetDescription().Label, id) {
+			return d
+		}
+	}
+	return devices.Find(id)
+}
+
+func label(d types.BaseVirtualDevice) string {
+	if info := d.GetVirtualDevice().DeviceInfo; info != nil {
+		return info.GetDescription().Label
+	}
+	return fmt.Sprintf("device %d", d.GetVirtualDevice().Key)
+}
+
+// summary is the device summary (the network of a NIC, the size of a disk), or "-" without DeviceInfo
+func summary(d types.BaseVirtualDevice) string {
+	if info := d.GetVirtualDevice().DeviceInfo; info != nil {
+		return info.GetDescription().Summary
+	}
+	return "-"
+}
+
+//
+// Change is a planned reconfiguration - Spec collects everything for the one Reconfigure call, Lines is what
+// gets printed and Problems are the reasons it cannot go ahead
+//
+
+type Change struct {
+	Spec     types.VirtualMachineConfigSpec
+	Lines    []string
+	Problems []string
+}
+
+func (ch *Change) line(format string, args ...interface{}) {
+	ch.Lines = append(ch.Lines, fmt.Sprintf(format, args...))
+}
+
+func (ch *Change) problem(format string, args ...interface{}) {
+	ch.Problems = append(ch.Problems, fmt.Sprintf(format, args...))
+}
+
+func (ch *Change) device(op types.VirtualDeviceConfigSpecOperation, file types.VirtualDeviceConfigSpecFileOperation, d types.BaseVirtualDevice) {
+	ch.Spec.DeviceChange = append(ch.Spec.DeviceChange, &types.VirtualDeviceConfigSpec{
+		Operation:     op,
+		FileOperation: file,
+		Device:        d,
+	})
+}
+
+//
+// sizing plans the vCPU, memory, reservation and hot add changes. A running VM can only grow, and only where
+// hot add (or CPU hot remove) is enabled - vSphere would refuse anything else.
+//
+
+func sizing(ch *Change, vm *mo.VirtualMachine, cpu, cores int32, mem units.ByteSize, cpuRsv int64, memRsv units.ByteSize, hotAdd string) {
+	on := vm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn
+	hw := vm.Config.Hardware
+
+	if cpu > 0 && cpu != hw.NumCPU {
+		ch.Spec.NumCPUs = cpu
+		ch.line("~ vCPU %d -> %d", hw.NumCPU, cpu)
+		if on && cpu > hw.NumCPU && !isTrue(vm.Config.CpuHotAddEnabled) {
+			ch.problem("the VM is powered on and CPU hot add is not enabled")
+		}
+		if on && cpu < hw.NumCPU && !isTrue(vm.Config.CpuHotRemoveEnabled) {
+			ch.problem("the VM is powered on and CPU hot remove is not enabled")
+		}
+	}
+
+	if cores > 0 && cores != hw.NumCoresPerSocket {
+		ch.Spec.NumCoresPerSocket = cores
+		ch.line("~ cores per socket %d -> %d", hw.NumCoresPerSocket, cores)
+		if on {
+			ch.problem("cores per socket can only be changed with the VM powered off")
+		}
+	}
+
+	if mb := int64(mem / units.MB); mb > 0 && mb != int64(hw.MemoryMB) {
+		ch.Spec.MemoryMB = mb
+		ch.line("~ memory %s -> %s", units.ByteSize(int64(hw.MemoryMB)*units.MB), mem)
+		if on && mb < int64(hw.MemoryMB) {
+			ch.problem("memory cannot be reduced while the VM is powered on")
+		}
+		if on && mb > int64(hw.MemoryMB) && !isTrue(vm.Config.MemoryHotAddEnabled) {
+			ch.problem("the VM is powered on and memory hot add is not enabled")
+		}
+	}
+
+	if cpuRsv >= 0 {
+		old := int64(0)
+		if vm.Config.CpuAllocation != nil && vm.Config.CpuAllocation.Reservation != nil {
+			old = *vm.Config.CpuAllocation.Reservation
+		}
+		if cpuRsv != old {
+			ch.Spec.CpuAllocation = &types.ResourceAllocationInfo{Reservation: &cpuRsv}
+			ch.line("~ CPU reservation %dMHz -> %dMHz", old, cpuRsv)
+		}
+	}
+
+	if memRsv >= 0 {
+		old := int64(0)
+		if vm.Config.MemoryAllocation != nil && vm.Config.MemoryAllocation.Reservation != nil {
+			old = *vm.Config.MemoryAllocation.Reservation
+		}
+		if mb := int64(memRsv / units.MB); mb != old {
+			ch.Spec.MemoryAllocation = &types.ResourceAllocationInfo{Reservation: &mb}
+			ch.line("~ memory reservation %s -> %s", units.ByteSize(old*units.MB), memRsv)
+		}
+	}
+
+	if hotAdd != "" {
+		enable := hotAdd == "on"
+		if isTrue(vm.Config.CpuHotAddEnabled) != enable || isTrue(vm.Config.MemoryHotAddEnabled) != enable {
+			ch.Spec.CpuHotAddEnabled = &enable
+			ch.Spec.MemoryHotAddEnabled = &enable
+			ch.line("~ CPU/memory hot add -> %s", hotAdd)
+			if on {
+				ch.problem("hot add can only be changed with the VM powered off")
+			}
+		}
+	}
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+func main() {
+
+	// We need to get 3 environment variables:
+	//
+	//-- GOVMOMI_URL
+	//-- GOVMOMI_USERNAME
+	//-- GOVMOMI_PASSWORD
+
+	var vmArg, hotAdd, datastore, nicType string
+	var cpu, cores int
+	var cpuRsv int64
+	mem := units.ByteSize(0)
+	var memRsv units.ByteSize
+	var addDisks, extendDisks, removeDisks, addNics, removeNics stringList
+	var thin, deleteFiles, apply bool
+
+	flag.StringVar(&vmArg, "vm", "", "VM name or inventory path")
+	flag.IntVar(&cpu, "cpu", 0, "number of vCPUs")
+	flag.IntVar(&cores, "cores", 0, "cores per socket")
+	flag.Var(&mem, "mem", "memory size, e.g. 8GB")
+	flag.Int64Var(&cpuRsv, "cpu-reservation", 0, "CPU reservation in MHz")
+	flag.Var(&memRsv, "mem-reservation", "memory reservation, e.g. 4GB")
+	flag.StringVar(&hotAdd, "hot-add", "", "enable (on) or disable (off) CPU and memory hot add")
+	flag.Var(&addDisks, "add-disk", "add a disk of this size, e.g. 20GB (repeatable)")
+	flag.StringVar(&datastore, "datastore", "", "datastore for new disks (default: the VM's)")
+	flag.BoolVar(&thin, "thin", true, "thin provision new disks")
+	flag.Var(&extendDisks, "extend-disk", "grow a disk, as \"label=size\" (repeatable)")
+	flag.Var(&removeDisks, "remove-disk", "remove the disk with this label (repeatable)")
+	flag.BoolVar(&deleteFiles, "delete-files", false, "delete the files of removed disks rather than keeping them")
+	flag.Var(&addNics, "add-nic", "add a network adapter on this network or port group (repeatable)")
+	flag.StringVar(&nicType, "nic-type", "vmxnet3", "adapter type for -add-nic: vmxnet3, e1000e, e1000, ...")
+	flag.Var(&removeNics, "remove-nic", "remove the network adapter with this label (repeatable)")
+	flag.BoolVar(&apply, "apply", false, "make the changes rather than only printing them")
+	flag.Parse()
+
+	// A reservation of 0 is a change like any other, so -1 stands for a reservation flag that was not given
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if !given["cpu-reservation"] {
+		cpuRsv = -1
+	}
+	if !given["mem-reservation"] {
+		memRsv = -1
+	}
+
+	if vmArg == "" {
+		fmt.Printf("usage: set-vm -vm name|path [changes...] [-apply]\n")
+		return
+	}
+	if hotAdd != "" && hotAdd != "on" && hotAdd != "off" {
+		fmt.Printf("-hot-add must be on or off\n")
+		return
+	}
+
+	vc := os.Getenv("GOVMOMI_URL")
+	user := os.Getenv("GOVMOMI_USERNAME")
+	pwd := os.Getenv("GOVMOMI_PASSWORD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := vlogin(ctx, vc, user, pwd)
+	if err != nil {
+		return
+	}
+
+	ref, err := findObject(ctx, c, "VirtualMachine", vmArg)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+
+	var vm mo.VirtualMachine
+	err = property.DefaultCollector(c).RetrieveOne(ctx, ref, []string{"name", "config", "runtime.powerState", "datastore"}, &vm)
+	if err != nil {
+		fmt.Printf("Could not retrieve VM configuration, error %v\n", err)
+		return
+	}
+	if vm.Config == nil {
+		fmt.Printf("%s has no configuration (inaccessible or orphaned?)\n", vm.Name)
+		return
+	}
+
+	ch := new(Change)
+	sizing(ch, &vm, int32(cpu), int32(cores), mem, cpuRsv, memRsv, hotAdd)
+
+	devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
+
+	//
+	// Disks. New disks go on the first free slot of an existing controller, in the VM's folder on -datastore or
+	// on the VM's own datastore. Each new device is added to the list so the next one gets its own slot and key.
+	//
+
+	if len(addDisks) > 0 {
+		var ds types.ManagedObjectReference
+		if datastore != "" {
+			ds, err = findObject(ctx, c, "Datastore", datastore)
+		} else if len(vm.Datastore) > 0 {
+			ds = vm.Datastore[0]
+		} else {
+			err = fmt.Errorf("%s is on no datastore, use -datastore", vm.Name)
+		}
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+
+		for _, s := range addDisks {
+			var size units.ByteSize
+			if err = size.Set(s); err != nil || size <= 0 {
+				fmt.Printf("-add-disk wants a size such as 20GB, got %q\n", s)
+				return
+			}
+
+			controller, err := devices.FindDiskController("")
+			if err != nil {
+				ch.problem("no disk controller with a free slot for a new disk")
+				break
+			}
+
+			disk := devices.CreateDisk(controller, ds, "")
+			disk.CapacityInKB = int64(size / units.KB)
+			disk.CapacityInBytes = int64(size)
+			if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok {
+				backing.ThinProvisioned = &thin
+			}
+			devices = append(devices, disk)
+
+			ch.device(types.VirtualDeviceConfigSpecOperationAdd, types.VirtualDeviceConfigSpecFileOperationCreate, disk)
+			ch.line("+ disk %s (thin %t) on %s", size, thin, devices.Name(controller.(types.BaseVirtualDevice)))
+		}
+	}
+
+	for _, s := range extendDisks {
+		i := strings.LastIndex(s, "=")
+		var size units.ByteSize
+		if i <= 0 || size.Set(s[i+1:]) != nil {
+			fmt.Printf("-extend-disk wants \"label=size\", got %q\n", s)
+			return
+		}
+
+		disk, ok := findDevice(devices, s[:i]).(*types.VirtualDisk)
+		if !ok {
+			ch.problem("no disk called %s", s[:i])
+			continue
+		}
+
+		old := units.ByteSize(disk.CapacityInKB * units.KB)
+		if size < old {
+			ch.problem("%s is %s - disks can only grow", label(disk), old)
+			continue
+		}
+		if size == old {
+			continue
+		}
+
+		disk.CapacityInKB = int64(size / units.KB)
+		disk.CapacityInBytes = int64(size)
+		ch.device(types.VirtualDeviceConfigSpecOperationEdit, "", disk)
+		ch.line("~ %s %s -> %s", label(disk), old, size)
+	}
+
+	for _, id := range removeDisks {
+		disk, ok := findDevice(devices, id).(*types.VirtualDisk)
+		if !ok {
+			ch.problem("no disk called %s", id)
+			continue
+		}
+
+		file := types.VirtualDeviceConfigSpecFileOperation("")
+		what := "files kept"
+		if deleteFiles {
+			file = types.VirtualDeviceConfigSpecFileOperationDestroy
+			what = "files deleted"
+		}
+		ch.device(types.VirtualDeviceConfigSpecOperationRemove, file, disk)
+		ch.line("- %s (%s, %s)", label(disk), units.ByteSize(disk.CapacityInKB*units.KB), what)
+	}
+
+	//
+	// Network adapters
+	//
+
+	for _, name := range addNics {
+		nref, err := findObject(ctx, c, "Network", name)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+
+		network, ok := object.NewReference(c, nref).(object.NetworkReference)
+		if !ok {
+			fmt.Printf("%s is not a network\n", name)
+			return
+		}
+
+		backing, err := network.EthernetCardBackingInfo(ctx)
+		if err != nil {
+			fmt.Printf("Could not get the backing for %s, error %v\n", name, err)
+			return
+		}
+
+		nic, err := devices.CreateEthernetCard(nicType, backing)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		devices = append(devices, nic)
+
+		ch.device(types.VirtualDeviceConfigSpecOperationAdd, "", nic)
+		ch.line("+ %s network adapter on %s", nicType, name)
+	}
+
+	for _, id := range removeNics {
+		d := findDevice(devices, id)
+		if _, ok := d.(types.BaseVirtualEthernetCard); !ok {
+			ch.problem("no network adapter called %s", id)
+			continue
+		}
+
+		ch.device(types.VirtualDeviceConfigSpecOperationRemove, "", d)
+		ch.line("- %s (%s)", label(d), summary(d))
+	}
+
+	//
+	// Print the plan, then make the one Reconfigure call
+	//
+
+	fmt.Printf("\n%s (%s)\n", vm.Name, vm.Runtime.PowerState)
+
+	if len(ch.Lines) == 0 && len(ch.Problems) == 0 {
+		fmt.Printf("= nothing to change\n")
+		return
+	}
+
+	for _, l := range ch.Lines {
+		fmt.Printf("%s\n", l)
+	}
+	for _, p := range ch.Problems {
+		fmt.Printf("! %s\n", p)
+	}
+
+	if len(ch.Problems) > 0 {
+		fmt.Printf("\nNot reconfiguring %s\n", vm.Name)
+		os.Exit(1)
+	}
+
+	if !apply {
+		fmt.Printf("\nDry run - re-run with -apply to make these changes\n")
+		return
+	}
+
+	task, err := object.NewVirtualMachine(c, ref).Reconfigure(ctx, ch.Spec)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil {
+		fmt.Printf("\nReconfigure failed, error %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nReconfigured %s\n", vm.Name)
+}